@@ -0,0 +1,169 @@
+import { afterAll, beforeAll, describe, expect, test } from "bun:test";
+import { execFileSync } from "child_process";
+import { mkdtempSync, rmSync } from "fs";
+import { tmpdir } from "os";
+import { join } from "path";
+import {
+  buildLogCommand,
+  fetchLogsForAuthor,
+  parseLogRecord,
+  parseOrigin,
+} from "./who";
+
+// Throwaway repository the tests run in, with an "origin" remote so
+// remote-tracking refs in decorations are recognized
+let fixture: string;
+const startDir = process.cwd();
+
+// Run git in the fixture repository
+const git = (...args: string[]): string =>
+  execFileSync("git", args, { cwd: fixture, encoding: "utf8" }).trim();
+
+beforeAll(() => {
+  fixture = mkdtempSync(join(tmpdir(), "git-who-test-"));
+  git("init", "--quiet", "--initial-branch=main");
+  git("config", "user.name", "Jane Doe");
+  git("config", "user.email", "jane@example.com");
+  git("remote", "add", "origin", "https://github.com/example/project.git");
+  process.chdir(fixture);
+});
+
+afterAll(() => {
+  process.chdir(startDir);
+  rmSync(fixture, { recursive: true, force: true });
+});
+
+// Join the fields of one commit the way getLogFormat lays them out, followed
+// by any --numstat lines
+const record = (
+  {
+    hash = "a1b2c3d",
+    subject = "Add login form",
+    refs = "",
+  }: { hash?: string; subject?: string; refs?: string },
+  numstat: string[] = []
+): string =>
+  [
+    [
+      hash,
+      subject,
+      "2026-10-01T12:00:00+00:00",
+      "2 weeks ago",
+      "Jane Doe",
+      "jane@example.com",
+      refs,
+    ].join("\x1f"),
+    ...numstat,
+  ].join("\n");
+
+describe("parseLogRecord", () => {
+  test.each([
+    {
+      name: "merge commit",
+      input: record({
+        subject: "Merge branch 'feature/login' into main",
+        refs: "HEAD -> main, origin/main, origin/HEAD",
+      }),
+      commitMessage: "Merge branch 'feature/login' into main",
+      origin: "origin/main",
+    },
+    {
+      name: "message with many spaces and a pipe",
+      input: record({
+        subject: "fix:  keep   spacing | and pipes  intact",
+        refs: "origin/main",
+      }),
+      commitMessage: "fix:  keep   spacing | and pipes  intact",
+      origin: "origin/main",
+    },
+    {
+      name: "undecorated commit",
+      input: record({ subject: "Refactor the table renderer" }),
+      commitMessage: "Refactor the table renderer",
+      origin: null,
+    },
+    {
+      name: "commit decorated with a tag only",
+      input: record({ subject: "Release 1.0", refs: "tag: v1.0" }),
+      commitMessage: "Release 1.0",
+      origin: null,
+    },
+  ])("$name", ({ input, commitMessage, origin }) => {
+    const entry = parseLogRecord(input, { timeZone: "UTC" });
+
+    expect(entry.commitHash).toBe("a1b2c3d");
+    expect(entry.commitMessage).toBe(commitMessage);
+    expect(entry.origin).toBe(origin);
+    expect(entry.authorName).toBe("Jane Doe");
+    expect(entry.authorEmail).toBe("jane@example.com");
+    expect(entry.date).toBe("2026-10-01");
+  });
+
+  test("numstat lines after the commit", () => {
+    const entry = parseLogRecord(
+      record({}, ["3\t1\tsrc/login.ts", "-\t-\tassets/logo.png"]),
+      { stat: true, timeZone: "UTC" }
+    );
+
+    expect(entry.files).toEqual(["src/login.ts", "assets/logo.png"]);
+    expect(entry.filesChanged).toBe(2);
+    expect(entry.insertions).toBe(3);
+    expect(entry.deletions).toBe(1);
+  });
+});
+
+describe("parseOrigin", () => {
+  test.each<[string, string, string | null]>([
+    ["no decoration", "", null],
+    ["local-only branch", "HEAD -> main", null],
+    ["several local branches", "HEAD -> main, feature/login", null],
+    ["local branch and tag", "feature/login, tag: v1.0", null],
+    ["remote-only branch", "origin/release", "origin/release"],
+    ["remote HEAD only", "origin/HEAD", null],
+    [
+      "local and remote branches",
+      "HEAD -> main, origin/main, origin/HEAD",
+      "origin/main",
+    ],
+    ["second remote", "upstream/dev", "upstream/dev"],
+    ["unknown remote", "fork/dev", null],
+  ])("%s", (_, refNames, origin) => {
+    expect(parseOrigin(refNames, ["origin", "upstream"])).toBe(origin);
+  });
+});
+
+describe("--since", () => {
+  // Commit in the fixture with author and committer dates set to daysAgo
+  const commitDaysAgo = (subject: string, daysAgo: number): string => {
+    const date = new Date(Date.now() - daysAgo * 86_400_000).toISOString();
+    execFileSync("git", ["commit", "--quiet", "--allow-empty", "-m", subject], {
+      cwd: fixture,
+      env: { ...process.env, GIT_AUTHOR_DATE: date, GIT_COMMITTER_DATE: date },
+    });
+    return git("rev-parse", "--short", "HEAD");
+  };
+
+  let recent: string;
+  let old: string;
+
+  beforeAll(() => {
+    old = commitDaysAgo("Set up the project", 30);
+    recent = commitDaysAgo("Add the login page", 2);
+  });
+
+  test("is passed to git without quotes", () => {
+    expect(buildLogCommand(["Jane Doe"], "1 week ago", {})).toContain(
+      "--since=1 week ago"
+    );
+  });
+
+  test("returns the commits inside a realistic range", async () => {
+    const logs = await fetchLogsForAuthor(["Jane Doe"], "1 week ago", {
+      quiet: true,
+    });
+    const hashes = logs.map((log) => log.commitHash);
+
+    expect(hashes).toContain(recent);
+    expect(hashes).not.toContain(old);
+  });
+});
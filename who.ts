@@ -1,28 +1,151 @@
 #!/usr/bin/env bun
-import { execSync } from "child_process";
+import {
+  execFile,
+  execFileSync,
+  execSync,
+  spawn,
+  spawnSync,
+} from "child_process";
+import { createHash } from "crypto";
+import {
+  appendFileSync,
+  existsSync,
+  mkdirSync,
+  readFileSync,
+  writeFileSync,
+} from "fs";
+import { homedir } from "os";
+import { dirname, join, resolve } from "path";
+import { promisify } from "util";
 import inquirer from "inquirer";
 import ora from "ora";
+import chalk, { foregroundColorNames, type ForegroundColorName } from "chalk";
 import Table from "cli-table3";
 
-// Function to display help documentation
-const displayHelp = (): void => {
-  console.log(`
+// Help text, also scanned for the flags offered by shell completion
+const HELP_TEXT = `
   Git Who - Custom Git Logs Tool
   ================================
   
   A simple tool to view Git logs based on the author and time range.
   
   Usage:
-    git who [author_name] [--t] [--T]
+    git who [author_name...] [--t] [--T[=range]] [options]
+    git who top [--T[=range]] [--top=n] [--min-commits=n]
+    git who bus-factor [--T[=range]] [--coverage=percent]
+    git who standup [--since=date]
+    git who heatmap [author_name...] [--t]
+    git who summary [--T[=range]]
+    git who blame <file>
+    git who churn [--T[=range]] [--author=name] [--top=n]
+    git who inactive [--threshold=date] [--all]
+    git who onboarding [--T[=range]] [--all]
+    git who compare <author> <author> [--t] [--T[=range]]
+    git who show <commit>
+    git who open [commit] [--t] [--T[=range]]
+    git who email-report [author_name...] [--team] [--T[=range]] [--html]
+    git who search <text> [--author=name] [-i]
+    git who tags [author_name...] [--t]
+    git who diff [author_name...] [--t] [--T[=range]] [--path=pathspec]
+    git who config <get|set|list> [key] [value]
+    git who completion <bash|zsh|fish|powershell>
+
+  Commands:
+    top                          Show a leaderboard of commit counts and % of total per author (--top=n or --max=n, default 10; --min-commits=n).
+    bus-factor                   Show the fewest authors who made --coverage percent (default 80) of the commits in the time range.
+    standup                      Show your own commits on all branches since yesterday (--since=date to override).
+    heatmap                      Show a GitHub-style grid of daily commit counts over the last year.
+    summary                      Show total commits, contributors, first/last commit and the busiest author.
+    blame <file>                 Show how many of a file's current lines each author owns.
+    churn                        Show the files changed most often in the time range (--author=name, --top=n).
+    inactive                     List contributors whose last commit is older than --threshold (default: 6 months ago).
+    onboarding                   List first-time contributors: authors whose first ever commit is in the time range.
+    compare                      Compare two authors' commits, lines changed and files touched in the time range.
+    show <commit>                Show a commit's author, date, full message and changed files; hash prefixes are accepted.
+    open [commit]                Open a commit on GitHub or GitLab in the browser, or pick one of your recent commits.
+    email-report                 Print a digest of commits grouped by author and day, as text or HTML (--html, --team).
+    search <text>                Search all authors' commit messages on every branch, most recent first (--author=name, -i, --regex).
+    tags                         List the tags an author created or whose tagged commit they wrote.
+    diff                         Show the full changes (patches) of an author's commits in the time range.
+    config                       Show or change the defaults in the config file, e.g. git who config set theme dracula.
+    completion <shell>           Print a tab completion script for bash, zsh, fish or powershell.
 
   Options:
-    [author_name]    Specify the author's name to view their logs (default is the current user).
-    --t              Enable interactive mode to select an author from the contributors.
-    --T              Enable interactive time selection (choose from options like "1 week ago", "1 month ago", etc.).
-    --help           Show this help message and exit.
+    [author_name...]             Specify one or more authors to view their logs (default is the current user).
+    --co-authors                 Also match commits where the author is credited in a Co-authored-by: trailer.
+    --exact                      Only match the names as given, not the other names used with the same email.
+    --no-mailmap                 Show and match authors as recorded in each commit, ignoring .mailmap.
+    --author-regex               Treat author names as extended regular expressions instead of literal text (--grep patterns too).
+    --me                         Include your own commits (same as passing "me" as an author).
+    --t                          Enable interactive mode to select one or more authors from the contributors.
+    --refresh                    Rescan the history for the --t contributor list instead of using the cache.
+    --T                          Enable interactive time selection (choose from options like "1 week ago", "1 month ago", etc.).
+    --T=<range>                  Use any time range git understands, e.g. "yesterday", "3 days ago" or 2024-01-01.
+    --until=<date>               Only show commits older than the given date (default: no upper bound).
+    --since-tag=<tag>            Show commits made after a release tag (replaces the time range).
+    --until-tag=<tag>            Show commits up to and including a release tag.
+    --since-branch-point=<base>  Show commits made since HEAD (or --branch) forked from base, e.g. main.
+    --first                      Show only the author's first (oldest) commit across the whole history.
+    --limit=<n>, -n <n>          Show at most n commits (default: 50, 0 for no limit).
+    --branch=<name>              Show commits on another branch without checking it out.
+    --all                        Show commits from every branch and tag (with --t, also list their contributors).
+    --include-remote             Also show commits only reachable from remote-tracking branches.
+    --first-parent               Only follow the first parent of merges, showing the mainline history.
+    --no-merges                  Hide merge commits.
+    --merges-only                Only show merge commits.
+    --include-merges             Count merge commits in --count-only, top, bus-factor and summary, which leave them out by default.
+    --path=<pathspec>            Only show commits touching this file or directory (can be repeated).
+    --follow                     Follow the --path file across renames (needs exactly one --path).
+    --grep=<pattern>             Only show commits whose message matches the pattern, a basic regex (extended with --author-regex).
+    --grep-i=<pattern>           Same as --grep, but case-insensitive.
+    --group-by=<period>          Split the table into one section per day, week or month, with commit counts.
+    --week-start=<day>           Start weeks on monday (default) or sunday, for --group-by=week and --T="this week".
+    --order=<order>              Order commits as git's --topo-order (topo), --date-order (date) or --author-date-order (author-date).
+    --reverse                    Show the oldest commits first; with -n, the n oldest commits in the range.
+    --sort=<key>                 Sort commits by date (oldest first), -date (newest first), message or hash.
+    --max-message-width=<n>      Truncate messages after n characters instead of fitting the terminal (0: never).
+    --full-hash                  Show full 40-character commit hashes instead of abbreviated ones.
+    --date-format=<layout>       Format dates with tokens such as YYYY, MM, DD, MMM, ddd, HH and mm (default: YYYY-MM-DD).
+    --date-kind=<kind>           Show the author date (default) or the committer date, which differs after rebases.
+    --tz=<zone>                  Show commit dates in a time zone, e.g. UTC or Europe/Berlin (default: Local).
+    --relative                   Show commit ages such as "3 days ago" instead of dates.
+    --columns=<list>             Only show these table columns, in this order (e.g. hash,date,message).
+    --no-origin                  Hide the Origin column.
+    --email                      Add an Email column with each commit's author email.
+    --show-signature             Add a column showing whether each commit is signed: ✔ good, ✘ bad or unsigned, ? unknown.
+    --dco                        Add a DCO column showing whether each commit has a Signed-off-by trailer.
+    --dco-missing                Only show commits without a Signed-off-by trailer.
+    --flag-fixups                Highlight fixup!/squash! commits that still need to be squashed, and count them.
+    --fixups-only                Only show fixup!/squash! commits.
+    --stat                       Add Files, + and - columns with the size of each commit.
+    --files, --paths-changed     Add a Paths column counting the files each commit changed; --format lists them under each line.
+    --summary                    Print a footer with the total commits, lines added/removed, files touched and date span.
+    --theme=<name>               Color scheme for tables: default, dracula, solarized or mono (no table colors).
+    --work-hours[=h-h]           After the table, count commits on weekdays in working hours (default 9-17), other hours and weekends. A rough signal only: commit times are not work times.
+    --no-color                   Disable colors in the output (also honored via the NO_COLOR variable).
+    --no-links                   Do not turn commit hashes into links to GitHub or GitLab.
+    --no-pager                   Print long tables directly instead of opening them in $PAGER.
+    --watch                      Redraw the table every few seconds as a live view (--interval=<seconds>, default 5).
+    --interactive                After the table, pick commits one by one to see their full message and changed files.
+    --non-interactive            Never prompt: --T falls back to the default range, and --t, --pick and --interactive fail.
+    --pick                       After the table, pick a commit and copy its hash to the clipboard.
+    --output=<file>, -o <file>   Write the output (any format) to a file instead of stdout, without colors.
+    --json, -j                   Print the logs as a JSON array instead of a table (no colors or headers).
+    --body                       Include each commit's message body in --json, --ndjson and --format output.
+    --ndjson                     Stream the logs as JSON Lines, one object per commit, as git finds them.
+    --csv[=file]                 Write the logs as CSV to stdout, or to the given file.
+    --tsv, --porcelain           Print hash, date, author, message and origin as tab-separated lines, without a header or colors.
+    --markdown                   Print the logs as a GitHub-flavored Markdown table for PRs and issues.
+    --format=<template>          Print one line per commit from a template, e.g. '{{.CommitHash}} {{.CommitMessage}}'.
+    --count-only                 Print only the number of matching commits, not counting merges (see --include-merges).
+    --dry-run                    Print the git log command that would run, without running it.
+    --timeout=<seconds>          Stop git and fail if reading the history takes longer, in any command (default 30, 0 for no limit).
+    --repo=<path>                Run against the repository at path instead of the current directory.
+    --help                       Show this help message and exit.
 
   Interactive Options:
     --t and --T are optional flags that can be used together to interactively select both the author and the time range.
+    The --T picker ends with "Other (type a value)" for any time range git understands.
 
   Examples:
     1. Default: View the logs of the current user in the last week:
@@ -37,6 +160,97 @@ const displayHelp = (): void => {
     4. Interactive mode to select an author and a time range:
        git who --t --T
 
+    5. Pipe the logs of a specific author into jq:
+       git who "Author Name" --json | jq '.[].commitHash'
+
+    6. Export the logs of a specific author to a spreadsheet:
+       git who "Author Name" --csv=logs.csv
+
+    7. View the logs of the current user since yesterday:
+       git who --T=yesterday
+
+    8. View the logs of the current user between two and one month ago:
+       git who --T="2 months ago" --until="1 month ago"
+
+    9. See who recently touched a file:
+       git who "Author Name" --path=src/index.ts
+
+    10. Show the ten most active contributors of the last month:
+       git who top --T="1 month ago"
+
+    11. Prepare for a Monday standup:
+       git who standup --since=friday
+
+    12. Compare the logs of two authors:
+       git who "Author One" "Author Two"
+
+    13. Copy a summary of a specific author's work into a PR description:
+       git who "Author Name" --markdown
+
+    14. Compare your own commits with a teammate:
+       git who me "Author Name"
+
+    15. Show your contribution heatmap for the last year:
+       git who heatmap
+
+    16. Count a specific author's commits this month:
+       git who "Author Name" --T="1 month ago" --count-only
+
+    17. See who owns most of a file right now:
+       git who blame src/index.ts
+
+    18. List your commits between two releases:
+       git who --since-tag=v1.0 --until-tag=v1.1
+
+    19. Save your week as a Markdown file:
+       git who --markdown -o notes/week.md
+
+    20. Find the files that changed most this month:
+       git who churn --T="1 month ago"
+
+    21. See your last month as a weekly timeline:
+       git who --T="1 month ago" --group-by=week
+
+    22. Find when someone made their first commit:
+       git who "Author Name" --first
+
+    23. Read everything an author changed in a file this week:
+       git who diff "Author Name" --path=src/index.ts
+
+    24. Print hashes and subjects for a script:
+       git who --format='{{.CommitHash}} {{.CommitMessage}}'
+
+    25. Find contributors who have not committed this year:
+       git who inactive --threshold="1 year ago"
+
+    26. Compare two contributors over the last month:
+       git who compare "Jane Doe" "John Doe" --T="1 month ago"
+
+    27. Write last week's team digest as HTML:
+       git who email-report --team --html -o digest.html
+
+  Configuration:
+    Defaults can be set in ~/.config/git-addons/config.yaml (command-line flags win):
+      contributor: Jane Doe
+      timeRange: 2 weeks ago
+      limit: 100
+      theme: solarized
+      headColor: cyan
+      borderColor: gray
+      dateColor: yellow
+
+  Exit Codes:
+    0  Commits were found, or a command that does not query commits succeeded.
+    1  Something went wrong, e.g. not a git repository or an invalid flag.
+    2  The query worked but found nothing, e.g. the author did not commit in the time range. Subcommands follow the same rule.
+
+  Shell Completion:
+    bash        source <(git who completion bash)   (after git's own completion)
+    zsh         source <(git who completion zsh)
+    fish        git who completion fish | source
+    PowerShell  git who completion powershell | Out-String | Invoke-Expression
+    Add the line to your shell's startup file to keep completion enabled.
+
   Time Range Options (used with --T):
     "1 day ago"
     "1 week ago"
@@ -47,134 +261,4847 @@ const displayHelp = (): void => {
 
   For more information, refer to the documentation or visit the Git repository.
 
-  `);
+  `;
+
+// Function to display help documentation
+const displayHelp = (): void => {
+  console.log(HELP_TEXT);
+};
+
+// An error to show the user: what went wrong, an optional hint on how to fix
+// it, the underlying cause and the exit code. Thrown anywhere and printed
+// once by renderError, so every failure looks the same
+class WhoError extends Error {
+  readonly hint?: string;
+  readonly exitCode: number;
+
+  constructor(
+    message: string,
+    {
+      hint,
+      cause,
+      exitCode = 1,
+    }: { hint?: string; cause?: unknown; exitCode?: number } = {}
+  ) {
+    super(message, { cause });
+    this.name = "WhoError";
+    this.hint = hint;
+    this.exitCode = exitCode;
+  }
+}
+
+// Print an error in the shared style and return the exit code to use.
+// Unexpected errors are shown the same way, without a stack trace
+const renderError = (error: unknown): number => {
+  const whoError =
+    error instanceof WhoError
+      ? error
+      : new WhoError(error instanceof Error ? error.message : String(error));
+  const { cause } = whoError;
+
+  // An empty message means the failing tool already explained itself
+  if (whoError.message) {
+    const reason = cause instanceof Error ? `: ${cause.message.trim()}` : "";
+    console.error(chalk.red(`Error: ${whoError.message}${reason}`));
+  }
+  if (whoError.hint) {
+    console.error(whoError.hint);
+  }
+
+  return whoError.exitCode;
+};
+
+// Contributor lists are cached per repository and reused until HEAD moves
+const CACHE_PATH = join(
+  process.env.XDG_CACHE_HOME || join(homedir(), ".cache"),
+  "git-addons",
+  "contributors.json"
+);
+
+// Shape of the contributor cache file, keyed by repository path
+type ContributorCache = Record<
+  string,
+  { head: string; contributors: string[] }
+>;
+
+// Read the contributor cache, treating a missing or corrupt file as empty
+const readContributorCache = (): ContributorCache => {
+  try {
+    return JSON.parse(readFileSync(CACHE_PATH, "utf8")) as ContributorCache;
+  } catch (error) {
+    return {};
+  }
 };
 
-// Fetch contributors from the Git history
-const fetchContributors = (): string[] => {
+// Fetch contributors from the cache when HEAD has not moved since they were
+// stored (unless refresh is set), otherwise from the Git history. With all
+// set every ref is scanned, and the cache is keyed on a hash of all of them.
+// Lists with and without .mailmap are cached separately
+const fetchContributors = (
+  refresh: boolean = false,
+  all: boolean = false,
+  ignoreMailmap: boolean = false
+): string[] => {
+  let toplevel: string;
+  let head: string;
+
   try {
-    const contributors = execSync('git log --format="%an" | sort | uniq')
+    const options = { maxBuffer: MAX_BUFFER, timeout: gitTimeout };
+    toplevel = execSync("git rev-parse --show-toplevel", options)
       .toString()
-      .trim()
-      .split("\n");
+      .trim();
+    head = all
+      ? createHash("sha1")
+          .update(execSync("git rev-parse --all", options))
+          .digest("hex")
+      : execSync("git rev-parse HEAD", options).toString().trim();
+  } catch (error) {
+    throw isTimeout(error)
+      ? timeoutError()
+      : new WhoError("Could not fetch contributors", { cause: error });
+  }
+
+  const repo =
+    toplevel + (all ? " --all" : "") + (ignoreMailmap ? " --no-mailmap" : "");
+  const cache = readContributorCache();
+
+  if (!refresh && cache[repo]?.head === head) {
+    return cache[repo].contributors;
+  }
+
+  const contributors = scanContributors(all, ignoreMailmap);
+  cache[repo] = { head, contributors };
+
+  try {
+    mkdirSync(dirname(CACHE_PATH), { recursive: true });
+    writeFileSync(CACHE_PATH, JSON.stringify(cache));
+  } catch (error) {
+    // The cache only speeds up the picker, so failing to write it is fine
+  }
+
+  return contributors;
+};
+
+// Let git log map identities through .mailmap (the default), or turn that off
+// for --no-mailmap so commits show and match the names they were made with
+const getMailmapArgs = (ignoreMailmap: boolean = false): string[] => [
+  ignoreMailmap ? "--no-use-mailmap" : "--use-mailmap",
+];
+
+// Scan the Git history (of the current branch, or every ref) for contributor
+// names, merged through .mailmap unless ignoreMailmap is set
+const scanContributors = (
+  all: boolean = false,
+  ignoreMailmap: boolean = false
+): string[] => {
+  try {
+    const format = ignoreMailmap ? "%an" : "%aN";
+    const command = `git log${all ? " --all" : ""} --format="${format}"`;
+    const names = execSync(command, {
+      maxBuffer: MAX_BUFFER,
+      timeout: gitTimeout,
+    })
+      .toString()
+      .split("\n")
+      .map((name) => name.trim())
+      .filter((name) => name !== "");
+
+    return [...new Set(names)].sort((a, b) =>
+      a.localeCompare(b, undefined, { sensitivity: "base" })
+    );
+  } catch (error) {
+    throw isTimeout(error)
+      ? timeoutError()
+      : new WhoError("Could not fetch contributors", { cause: error });
+  }
+};
+
+// Directory git who was started in, before --repo changes it
+const INVOCATION_DIR = process.cwd();
+
+// Run against the repository given with --repo by making it the working
+// directory, so every git command that follows uses it. Shells leave a "~"
+// after --repo= alone, so a leading one is expanded here
+const useRepository = (args: string[]): void => {
+  const value = getFlagValue(args, "--repo");
+  if (value === undefined) {
+    return;
+  }
+
+  if (value.trim() === "") {
+    throw new WhoError("--repo requires a path, e.g. --repo ~/src/project");
+  }
+
+  const path = value.replace(/^~(?=$|\/)/, homedir());
+
+  if (!existsSync(path)) {
+    throw new WhoError(`--repo path "${path}" does not exist.`);
+  }
+
+  try {
+    execFileSync("git", ["-C", path, "rev-parse", "--is-inside-work-tree"], {
+      stdio: "ignore",
+    });
+  } catch {
+    throw new WhoError(`"${path}" is not a git repository.`);
+  }
+
+  process.chdir(path);
+};
+
+// Resolve a file path given on the command line against the directory git who
+// was started in, which differs from the working directory with --repo
+const fromInvocationDir = (path: string | undefined): string | undefined =>
+  path && process.cwd() !== INVOCATION_DIR
+    ? resolve(INVOCATION_DIR, path)
+    : path;
+
+// Function to check if we're in a Git repository
+const checkGitRepository = (): void => {
+  try {
+    execSync("git rev-parse --is-inside-work-tree", { stdio: "ignore" });
+  } catch (error) {
+    throw new WhoError("Not a git repository.");
+  }
+};
+
+// Check whether the repository has at least one commit
+const hasCommits = (): boolean => {
+  try {
+    execSync("git rev-parse --verify --quiet HEAD", { stdio: "ignore" });
+    return true;
+  } catch (error) {
+    return false;
+  }
+};
 
-    return contributors;
+// Read a single Git config value, or an empty string when it is not set
+const readGitConfig = (key: string): string => {
+  try {
+    return execSync(`git config ${key}`, {
+      stdio: ["ignore", "pipe", "ignore"],
+    })
+      .toString()
+      .trim();
   } catch (error) {
-    console.error("Error fetching contributors:", (error as Error).message);
-    process.exit(1);
+    return "";
+  }
+};
+
+let cachedCurrentUser: string | undefined;
+
+// Resolve the current user from user.name, falling back to user.email
+const getCurrentUser = (): string => {
+  if (cachedCurrentUser) {
+    return cachedCurrentUser;
+  }
+
+  const user = readGitConfig("user.name") || readGitConfig("user.email");
+
+  if (!user) {
+    throw new WhoError(
+      'Git user.name is not set. Pass an author name, use --t, or run: git config --global user.name "Your Name"'
+    );
   }
+
+  cachedCurrentUser = user;
+  return user;
 };
 
 // Types for log entries
 interface LogEntry {
-  hash: string;
-  message: string;
+  commitHash: string;
+  commitMessage: string;
   date: string;
+  timestamp: string;
+  relativeDate: string;
   authorName: string;
+  authorEmail: string;
+  origin: string | null;
+  files?: string[];
+  filesChanged?: number;
+  insertions?: number;
+  deletions?: number;
+  signature?: string;
+  coAuthors?: string[];
+  signedOffBy?: string[];
+  body?: string;
+  attribution?: "primary" | "co-author";
 }
 
-// Fetch logs for a specific author and time range
-const fetchLogsForAuthor = (author: string, timeRange: string): void => {
-  try {
-    const spinner = ora(`Fetching logs for ${author}...`).start();
+// Options controlling how logs are fetched
+interface FetchOptions {
+  quiet?: boolean;
+  until?: string;
+  limit?: number;
+  paths?: string[];
+  follow?: boolean;
+  grep?: string;
+  ignoreCase?: boolean;
+  all?: boolean;
+  includeRemote?: boolean;
+  firstParent?: boolean;
+  branch?: string;
+  stat?: boolean;
+  fullHash?: boolean;
+  merges?: "exclude" | "only";
+  timeZone?: string;
+  revisionRange?: string;
+  authorRegex?: boolean;
+  signature?: boolean;
+  coAuthors?: boolean;
+  dco?: boolean;
+  dcoMissing?: boolean;
+  fixupsOnly?: boolean;
+  fixedStrings?: boolean;
+  body?: boolean;
+  reverse?: boolean;
+  exact?: boolean;
+  dateKind?: DateKind;
+  ignoreMailmap?: boolean;
+  order?: CommitOrder;
+}
 
-    const logs = execSync(
-      `git log --author="${author}" --since="${timeRange}" --pretty=format:"%h|%s|%ad|%an" --date=short`
-    )
-      .toString()
-      .trim();
+// Which of a commit's two dates is shown: when it was written (author) or
+// when it was last applied, e.g. by a rebase or cherry-pick (committer)
+const DATE_KINDS = ["author", "committer"] as const;
+type DateKind = (typeof DATE_KINDS)[number];
 
-    spinner.succeed("Logs fetched successfully!");
+// Parse --date-kind, defaulting to the author date
+const parseDateKind = (value: string | undefined): DateKind => {
+  if (value === undefined) {
+    return "author";
+  }
 
-    if (logs) {
-      const table = new Table({
-        head: ["Hash", "Message", "Date", "Author"],
-        style: {
-          head: ["cyan"],
-          border: ["gray"],
-        },
-      });
+  if (!(DATE_KINDS as readonly string[]).includes(value)) {
+    throw new WhoError(
+      `--date-kind expects one of ${DATE_KINDS.join(", ")}, got "${value}".`
+    );
+  }
 
-      logs.split("\n").forEach((log) => {
-        const [hash, message, date, authorName] = log.split("|");
-        table.push([hash, message, date, authorName]);
-      });
+  return value as DateKind;
+};
 
-      console.log(`\nRecent logs for ${author}:`);
-      console.log(table.toString());
-    } else {
-      console.log(`\nNo logs found for ${author} in the past ${timeRange}.`);
+// Commit orders accepted by --order and the git log flag each one maps to
+const COMMIT_ORDERS = {
+  topo: "--topo-order",
+  date: "--date-order",
+  "author-date": "--author-date-order",
+} as const;
+type CommitOrder = keyof typeof COMMIT_ORDERS;
+
+// Parse --order, leaving git's default order when it is not given
+const parseOrder = (value: string | undefined): CommitOrder | undefined => {
+  if (value === undefined) {
+    return undefined;
+  }
+
+  if (!Object.hasOwn(COMMIT_ORDERS, value)) {
+    throw new WhoError(
+      `--order expects one of ${Object.keys(COMMIT_ORDERS).join(", ")}, got "${value}".`
+    );
+  }
+
+  return value as CommitOrder;
+};
+
+// Names of the configured remotes, read once per run
+let remoteNames: string[] | undefined;
+const getRemoteNames = (): string[] => {
+  if (remoteNames === undefined) {
+    try {
+      remoteNames = execFileSync("git", ["remote"], { encoding: "utf8" })
+        .split("\n")
+        .filter(Boolean);
+    } catch {
+      remoteNames = [];
     }
+  }
+  return remoteNames;
+};
+
+// Extract the remote-tracking ref (such as origin/main) from a %D ref names
+// decoration, or null when the commit is only on local branches or tags
+export const parseOrigin = (
+  refNames: string,
+  remotes: string[] = getRemoteNames()
+): string | null => {
+  const refs = refNames
+    .split(",")
+    .map((ref) => ref.trim())
+    .filter(
+      (ref) =>
+        remotes.some((remote) => ref.startsWith(`${remote}/`)) &&
+        !ref.endsWith("/HEAD")
+    );
+
+  return refs[0] ?? null;
+};
+
+// Fields are separated by the ASCII unit separator, which cannot appear in
+// commit subjects or ref names, so messages containing "|" parse safely.
+// Each commit starts with a record separator so --numstat lines can follow it
+const FIELD_SEPARATOR = "\x1f";
+const RECORD_SEPARATOR = "\x1e";
+// The signature status (%G?), Co-authored-by and Signed-off-by trailers come
+// last and are only requested when needed, since checking signatures runs gpg
+// for every commit. Trailers are joined with the group separator to stay on
+// one line. The message body (%b) spans several lines, so it goes at the very
+// end followed by the file separator, which marks where --numstat lines start
+const COAUTHOR_SEPARATOR = "\x1d";
+const BODY_END = "\x1c";
+const getLogFormat = (options: FetchOptions = {}): string =>
+  "%x1e" +
+  [
+    options.fullHash ? "%H" : "%h",
+    "%s",
+    options.dateKind === "committer" ? "%cI" : "%aI",
+    options.dateKind === "committer" ? "%cr" : "%ar",
+    // %aN and %aE map the author through .mailmap to their canonical identity
+    options.ignoreMailmap ? "%an" : "%aN",
+    options.ignoreMailmap ? "%ae" : "%aE",
+    "%D",
+    ...(options.signature ? ["%G?"] : []),
+    ...(options.coAuthors
+      ? ["%(trailers:key=Co-authored-by,valueonly,unfold,separator=%x1d)"]
+      : []),
+    ...(options.dco
+      ? ["%(trailers:key=Signed-off-by,valueonly,unfold,separator=%x1d)"]
+      : []),
+    ...(options.body ? ["%b%x1c"] : []),
+  ].join("%x1f");
+
+// Allow large histories (especially with --numstat) to be read in one go
+const MAX_BUFFER = 100 * 1024 * 1024;
+
+// Seconds a git command reading the history may run without --timeout
+const DEFAULT_TIMEOUT = 30;
+
+// Limit for the history-reading git commands in milliseconds, set from
+// --timeout in main; 0 lets them run as long as they need
+let gitTimeout = DEFAULT_TIMEOUT * 1000;
+
+// Parse --timeout=<seconds> into milliseconds, where 0 turns the limit off
+const parseTimeout = (value: string | undefined): number => {
+  if (value === undefined) {
+    return DEFAULT_TIMEOUT * 1000;
+  }
+
+  const seconds = Number(value);
+  if (value.trim() === "" || !Number.isFinite(seconds) || seconds < 0) {
+    throw new WhoError(
+      `--timeout expects a number of seconds (0 for no limit), got "${value}".`
+    );
+  }
+
+  return seconds * 1000;
+};
+
+// Check whether a git command failed because the timeout stopped it. Running
+// out of buffer also kills the process, but sets its own error code
+const isTimeout = (error: unknown): boolean => {
+  const { code, killed } = error as { code?: unknown; killed?: boolean };
+  return code === "ETIMEDOUT" || (killed === true && code == null);
+};
+
+// Error shown when git was stopped for running longer than --timeout
+const timeoutError = (): WhoError =>
+  new WhoError(
+    `git did not finish within ${gitTimeout / 1000}s and was stopped.`,
+    {
+      hint: "Narrow the query (e.g. with --T or --path) or allow more time with --timeout=<seconds>, where 0 means no limit.",
+    }
+  );
+
+// Default layout for dates, as printed by git's --date=short
+const DEFAULT_DATE_FORMAT = "YYYY-MM-DD";
+
+// Tokens understood by --date-format; month and weekday names follow the
+// system locale
+const DATE_TOKENS = /YYYY|MMMM|MMM|MM|DD|dddd|ddd|HH|mm|ss/g;
+
+// Format an ISO 8601 timestamp in a time zone (default local) using the
+// DATE_TOKENS layout
+const formatDateInZone = (
+  iso: string,
+  timeZone?: string,
+  format: string = DEFAULT_DATE_FORMAT
+): string => {
+  const date = new Date(iso);
+  if (Number.isNaN(date.getTime())) {
+    return iso;
+  }
+
+  const numeric = new Intl.DateTimeFormat("en-US", {
+    timeZone,
+    year: "numeric",
+    month: "2-digit",
+    day: "2-digit",
+    hour: "2-digit",
+    minute: "2-digit",
+    second: "2-digit",
+    hourCycle: "h23",
+  }).formatToParts(date);
+  const part = (type: string) =>
+    numeric.find((entry) => entry.type === type)?.value ?? "";
+  const name = (options: Intl.DateTimeFormatOptions) =>
+    new Intl.DateTimeFormat(undefined, { timeZone, ...options }).format(date);
+
+  const tokens: Record<string, () => string> = {
+    YYYY: () => part("year"),
+    MMMM: () => name({ month: "long" }),
+    MMM: () => name({ month: "short" }),
+    MM: () => part("month"),
+    DD: () => part("day"),
+    dddd: () => name({ weekday: "long" }),
+    ddd: () => name({ weekday: "short" }),
+    HH: () => part("hour"),
+    mm: () => part("minute"),
+    ss: () => part("second"),
+  };
+
+  return format.replace(DATE_TOKENS, (token) => tokens[token]());
+};
+
+// Parse a single line of `git log --pretty=format:<getLogFormat()>` output
+const parseLogLine = (line: string, options: FetchOptions = {}): LogEntry => {
+  const [
+    commitHash,
+    commitMessage = "",
+    timestamp = "",
+    relativeDate = "",
+    authorName = "",
+    authorEmail = "",
+    refNames = "",
+    ...optional
+  ] = line.split(FIELD_SEPARATOR);
+  const entry: LogEntry = {
+    commitHash,
+    commitMessage,
+    date: formatDateInZone(timestamp, options.timeZone),
+    timestamp,
+    relativeDate,
+    authorName,
+    authorEmail,
+    origin: parseOrigin(refNames),
+  };
+
+  if (options.signature) {
+    entry.signature = optional.shift() ?? "";
+  }
+
+  if (options.coAuthors) {
+    entry.coAuthors = splitTrailers(optional.shift());
+  }
+
+  if (options.dco) {
+    entry.signedOffBy = splitTrailers(optional.shift());
+  }
+
+  if (options.body) {
+    entry.body = (optional.shift() ?? "").replace(BODY_END, "").trim();
+  }
+
+  return entry;
+};
+
+// Split the values of a repeated trailer, dropping empty ones
+const splitTrailers = (values: string = ""): string[] =>
+  values
+    .split(COAUTHOR_SEPARATOR)
+    .map((value) => value.trim())
+    .filter((value) => value !== "");
+
+// Reduce numstat's rename notation ("src/{a => b}.ts" or "a => b") to the
+// new path, so a renamed file is listed and counted once under its new name
+const resolveRenamedPath = (path: string): string =>
+  path.includes("{")
+    ? path.replace(/\{[^{}]* => ([^{}]*)\}/, "$1").replace(/\/{2,}/g, "/")
+    : path.replace(/^.* => /, "");
+
+// Parse one commit record: the formatted line followed by any --numstat lines
+export const parseLogRecord = (
+  record: string,
+  options: FetchOptions = {}
+): LogEntry => {
+  const trimmed = record.trim();
+  const bodyEnd = trimmed.indexOf(BODY_END) + 1;
+  const [line, ...numstat] = options.body
+    ? [trimmed.slice(0, bodyEnd), ...trimmed.slice(bodyEnd).split("\n")]
+    : trimmed.split("\n");
+  const entry = parseLogLine(line, options);
+
+  if (options.stat) {
+    const files = numstat.filter((row) => row.trim() !== "");
+    let insertions = 0;
+    let deletions = 0;
+
+    files.forEach((row) => {
+      // Binary files report "-" instead of line counts
+      const [added, deleted] = row.split("\t");
+      insertions += added === "-" ? 0 : Number(added);
+      deletions += deleted === "-" ? 0 : Number(deleted);
+    });
+
+    entry.files = files.map((row) =>
+      resolveRenamedPath(row.split("\t").slice(2).join("\t"))
+    );
+    entry.filesChanged = files.length;
+    entry.insertions = insertions;
+    entry.deletions = deletions;
+  }
+
+  return entry;
+};
+
+// git treats --author as a regular expression, so names are escaped to match
+// literally ("a.b" must not match "axb"). With --author-regex they are passed
+// through as extended regular expressions instead
+const getAuthorArgs = (authors: string[], regex: boolean = false): string[] =>
+  authors.length === 0
+    ? []
+    : regex
+    ? ["--extended-regexp", ...authors.map((author) => `--author=${author}`)]
+    : [
+        // The escaping assumes basic regexes, whatever grep.patternType says
+        "--basic-regexp",
+        ...authors.map(
+          (author) => `--author=${author.replace(/[\\.*^$[\]]/g, "\\$&")}`
+        ),
+      ];
+
+// Add the emails each author has committed with, so other spellings of their
+// name ("Bob" and "Bob Smith") match too. Emails are wrapped in <> so they
+// only match the whole address
+const expandIdentities = (
+  authors: string[],
+  ignoreMailmap: boolean = false
+): string[] => {
+  if (authors.length === 0) {
+    return authors;
+  }
+
+  try {
+    const emails = execFileSync(
+      "git",
+      [
+        "log",
+        "--all",
+        ...getMailmapArgs(ignoreMailmap),
+        `--format=${ignoreMailmap ? "%ae" : "%aE"}`,
+        ...getAuthorArgs(authors),
+      ],
+      { maxBuffer: MAX_BUFFER, timeout: gitTimeout }
+    )
+      .toString()
+      .split("\n")
+      .filter((email) => email !== "")
+      .map((email) => `<${email}>`);
+
+    return [...new Set([...authors, ...emails])];
   } catch (error) {
-    console.error("Error fetching logs:", (error as Error).message);
-    process.exit(1);
+    if (isTimeout(error)) {
+      throw timeoutError();
+    }
+    // Fall back to the names alone; the main query reports any git error
+    return authors;
   }
 };
 
-// Type for time range selection
-interface TimeRangeSelection {
-  selectedTimeRange: string;
-}
+// Keep the commits any author wrote or co-authored, marking which it was
+const attributeCoAuthors = (
+  logs: LogEntry[],
+  authors: string[],
+  options: FetchOptions
+): LogEntry[] => {
+  const flags = options.ignoreCase ? "i" : "";
+  const patterns = authors.map(
+    (author) =>
+      new RegExp(
+        options.authorRegex
+          ? author
+          : author.replace(/[.*+?^${}()|[\]\\]/g, "\\$&"),
+        flags
+      )
+  );
+  const matches = (identity: string) =>
+    patterns.some((pattern) => pattern.test(identity));
 
-// Type for author selection
-interface AuthorSelection {
-  selectedAuthor: string;
-}
+  const attributed = logs.flatMap((log): LogEntry[] => {
+    if (matches(`${log.authorName} <${log.authorEmail}>`)) {
+      return [{ ...log, attribution: "primary" }];
+    }
+    if (log.coAuthors?.some(matches)) {
+      return [{ ...log, attribution: "co-author" }];
+    }
+    return [];
+  });
 
-// Main function
-const main = async (): Promise<void> => {
-  const args = process.argv.slice(2);
+  return attributed;
+};
 
-  if (args.includes("--help")) {
-    displayHelp();
-    return;
+// Start a spinner on stderr, kept silent when stdout is not a terminal so
+// piped or redirected output stays clean
+const startSpinner = (text: string) =>
+  ora({ text, isSilent: !process.stdout.isTTY }).start();
+
+// Run git log without blocking, so the spinner keeps animating meanwhile.
+// Arguments are passed straight to git, never through a shell
+const execFileAsync = promisify(execFile);
+
+// Names also match the person's other spellings through their emails,
+// unless --exact or --author-regex asks for the patterns as given
+const resolveIdentities = (
+  authors: string[],
+  options: FetchOptions
+): string[] =>
+  options.exact || options.authorRegex
+    ? authors
+    : expandIdentities(authors, options.ignoreMailmap);
+
+// git applies -n before --reverse, and co-authors, missing sign-offs and
+// fixups are filtered afterwards, so in those cases the limit is applied once
+// the commits are parsed
+const isLimitInGit = (options: FetchOptions): boolean =>
+  !options.coAuthors &&
+  !options.reverse &&
+  !options.dcoMissing &&
+  !options.fixupsOnly;
+
+// Commits made with `git commit --fixup` or `--squash`, meant to be folded
+// into an earlier commit by `git rebase --autosquash` before shipping
+const isFixupCommit = (message: string): boolean =>
+  /^(fixup|squash|amend)! /.test(message);
+
+// Apply the filters git cannot do itself: co-author matching and, with
+// --dco-missing or --fixups-only, keeping only the commits in question
+const filterLogs = (
+  logs: LogEntry[],
+  identities: string[],
+  options: FetchOptions
+): LogEntry[] => {
+  const matched = options.coAuthors
+    ? attributeCoAuthors(logs, identities, options)
+    : logs;
+
+  return matched.filter(
+    (log) =>
+      (!options.dcoMissing || !log.signedOffBy?.length) &&
+      (!options.fixupsOnly || isFixupCommit(log.commitMessage))
+  );
+};
+
+// Build the `git log` arguments for the given author patterns and time range
+export const buildLogCommand = (
+  identities: string[],
+  timeRange: string,
+  options: FetchOptions
+): string[] => {
+  // git matches commits by any of the given authors. Co-authors only appear
+  // in the message, so then every commit is fetched and filtered here instead.
+  // With fixedStrings every pattern is literal text, so nothing is escaped
+  const command = [
+    "log",
+    ...(options.coAuthors
+      ? []
+      : options.fixedStrings
+      ? identities.map((identity) => `--author=${identity}`)
+      : getAuthorArgs(identities, options.authorRegex)),
+  ];
+
+  // A revision range such as v1.0..v2.0 replaces the time range, and an empty
+  // time range searches the whole history
+  if (!options.revisionRange && timeRange) {
+    command.push(`--since=${timeRange}`);
   }
 
-  const isInteractive = args.includes("--t");
-  const isTimeFlag = args.includes("--T");
+  if (options.until) {
+    command.push(`--until=${options.until}`);
+  }
 
-  let timeRange = "1 week ago"; // Default time range
+  if (options.all) {
+    command.push("--all");
+  } else if (options.includeRemote) {
+    command.push("--remotes");
+  }
 
-  if (isTimeFlag) {
-    // Prompt user for time range if --T is passed
-    const { selectedTimeRange } = await inquirer.prompt<TimeRangeSelection>([
-      {
-        type: "list",
-        name: "selectedTimeRange",
-        message: "Select a time range for the logs:",
-        choices: [
-          "1 day ago",
-          "1 week ago",
-          "2 weeks ago",
-          "1 month ago",
-          "3 months ago",
-          "6 months ago",
-        ],
-      },
-    ]);
-    timeRange = selectedTimeRange;
+  if (options.firstParent) {
+    command.push("--first-parent");
   }
 
-  if (isInteractive) {
-    const spinner = ora("Fetching contributors...").start();
-    const contributors = fetchContributors();
-    spinner.succeed("Contributors fetched!");
+  if (options.merges === "exclude") {
+    command.push("--no-merges");
+  } else if (options.merges === "only") {
+    command.push("--merges");
+  }
 
-    const { selectedAuthor } = await inquirer.prompt<AuthorSelection>([
-      {
-        type: "list",
-        name: "selectedAuthor",
-        message: "Select an author to view logs for:",
-        choices: contributors,
-      },
-    ]);
+  if (options.limit && isLimitInGit(options)) {
+    command.push("-n", String(options.limit));
+  }
 
-    fetchLogsForAuthor(selectedAuthor, timeRange);
-  } else {
-    const targetAuthor =
-      args[0] || execSync("git config user.name").toString().trim();
-    fetchLogsForAuthor(targetAuthor, timeRange);
+  if (options.order) {
+    command.push(COMMIT_ORDERS[options.order]);
+  }
+
+  if (options.reverse) {
+    command.push("--reverse");
+  }
+
+  if (options.grep) {
+    command.push(`--grep=${options.grep}`);
+    if (options.fixedStrings) {
+      command.push("--fixed-strings");
+    }
+    if (options.ignoreCase) {
+      command.push("-i");
+    }
+  }
+
+  if (options.stat) {
+    command.push("--numstat");
+  }
+
+  if (options.follow) {
+    command.push("--follow");
+  }
+
+  // Also decides whether --author matches the mapped or the recorded identity
+  command.push(...getMailmapArgs(options.ignoreMailmap));
+
+  command.push(`--pretty=format:${getLogFormat(options)}`);
+
+  if (options.revisionRange) {
+    command.push(options.revisionRange);
+  } else if (options.branch) {
+    command.push(options.branch);
+  } else if (options.includeRemote && !options.all) {
+    // --remotes replaces the default HEAD, so name it to keep local commits
+    command.push("HEAD");
+  }
+
+  if (options.paths?.length) {
+    command.push("--", ...options.paths);
+  }
+
+  return command;
+};
+
+// Fetch logs for one or more authors and a time range
+export const fetchLogsForAuthor = async (
+  authors: string[],
+  timeRange: string,
+  options: FetchOptions = {}
+): Promise<LogEntry[]> => {
+  const spinner = options.quiet
+    ? null
+    : startSpinner(`Fetching commits for ${authors.join(", ")}...`);
+
+  const identities = resolveIdentities(authors, options);
+  const command = buildLogCommand(identities, timeRange, options);
+
+  try {
+    const { stdout } = await execFileAsync("git", command, {
+      maxBuffer: MAX_BUFFER,
+      timeout: gitTimeout,
+    });
+    const logs = stdout.trim();
+
+    spinner?.succeed("Logs fetched successfully!");
+
+    if (!logs) {
+      return [];
+    }
+
+    const entries = logs
+      .split(RECORD_SEPARATOR)
+      .filter((record) => record.trim() !== "")
+      .map((record) => parseLogRecord(record, options));
+
+    const matched = filterLogs(entries, identities, options);
+
+    return options.limit && !isLimitInGit(options)
+      ? matched.slice(0, options.limit)
+      : matched;
+  } catch (error) {
+    spinner?.fail("Failed to fetch logs");
+    throw isTimeout(error)
+      ? timeoutError()
+      : new WhoError("Could not fetch logs", { cause: error });
   }
 };
 
-main();
+// Run the log query and hand each entry to onEntry as soon as git prints it,
+// without holding the whole history in memory. Resolves to the entry count
+const streamLogsForAuthor = (
+  authors: string[],
+  timeRange: string,
+  options: FetchOptions,
+  onEntry: (entry: LogEntry) => void
+): Promise<number> => {
+  const identities = resolveIdentities(authors, options);
+  const command = buildLogCommand(identities, timeRange, options);
+  const limit = options.limit && !isLimitInGit(options) ? options.limit : 0;
+
+  return new Promise((resolve, reject) => {
+    const child = spawn("git", command, {
+      stdio: ["ignore", "pipe", "inherit"],
+      timeout: gitTimeout,
+    });
+    let pending = "";
+    let count = 0;
+    let stopped = false;
+
+    // Parse one complete record, keeping only the commits that match
+    const handleRecord = (record: string): void => {
+      if (record.trim() === "" || (limit && count >= limit)) {
+        return;
+      }
+
+      const entry = parseLogRecord(record, options);
+      const matched = filterLogs([entry], identities, options);
+
+      matched.forEach((log) => {
+        count += 1;
+        onEntry(log);
+      });
+
+      if (limit && count >= limit) {
+        stopped = true;
+        child.kill();
+      }
+    };
+
+    child.stdout.setEncoding("utf8");
+    child.stdout.on("data", (chunk: string) => {
+      // A record is complete once the next record separator arrives
+      const records = (pending + chunk).split(RECORD_SEPARATOR);
+      pending = records.pop() ?? "";
+      records.forEach(handleRecord);
+    });
+
+    child.on("error", (error) => {
+      reject(new WhoError("Could not fetch logs", { cause: error }));
+    });
+
+    child.on("close", (code, signal) => {
+      handleRecord(pending);
+      // Only the timeout kills git without the limit having been reached
+      if (signal !== null && !stopped) {
+        reject(timeoutError());
+        return;
+      }
+      if (code !== 0 && signal === null) {
+        reject(
+          new WhoError(`Could not fetch logs: git log exited with code ${code}`)
+        );
+        return;
+      }
+      resolve(count);
+    });
+  });
+};
+
+// Create a table with the shared git who styling
+const createTable = (
+  head: string[],
+  display: DisplayOptions,
+  colWidths?: number[]
+) =>
+  new Table({
+    head,
+    ...(colWidths ? { colWidths } : {}),
+    style: display.color
+      ? {
+          head: display.colors.head ? [display.colors.head] : [],
+          border: display.colors.border ? [display.colors.border] : [],
+        }
+      : { head: [], border: [] },
+  });
+
+// Message column width when the terminal width is unknown (e.g. piped output)
+const DEFAULT_MESSAGE_WIDTH = 60;
+const MIN_MESSAGE_WIDTH = 20;
+
+// Size each column to its content, fitting the Message column to the
+// terminal so long messages are truncated with an ellipsis instead of wrapping.
+// An explicit maxMessageWidth replaces the terminal fit, and 0 never truncates
+const getColumnWidths = (
+  columns: LogColumn[],
+  logs: LogEntry[],
+  maxMessageWidth?: number
+): number[] => {
+  // cli-table3 widths include one space of padding on each side
+  const widths = columns.map(
+    (column) =>
+      Math.max(
+        column.header.length,
+        ...logs.flatMap((log) =>
+          column
+            .value(log)
+            .split("\n")
+            .map((line) => line.length)
+        )
+      ) + 2
+  );
+
+  const messageIndex = columns.findIndex(
+    (column) => column.name === "message"
+  );
+
+  if (messageIndex === -1) {
+    return widths;
+  }
+
+  if (maxMessageWidth !== undefined) {
+    if (maxMessageWidth > 0) {
+      widths[messageIndex] = Math.min(
+        widths[messageIndex],
+        maxMessageWidth + 2
+      );
+    }
+    return widths;
+  }
+
+  const otherWidths = widths.reduce(
+    (sum, width, index) => (index === messageIndex ? sum : sum + width),
+    0
+  );
+  const borders = columns.length + 1;
+  const available =
+    process.stdout.isTTY && process.stdout.columns
+      ? process.stdout.columns - otherWidths - borders
+      : DEFAULT_MESSAGE_WIDTH + 2;
+
+  widths[messageIndex] = Math.min(
+    widths[messageIndex],
+    Math.max(MIN_MESSAGE_WIDTH + 2, available)
+  );
+
+  return widths;
+};
+
+// Format a commit date as relative, with --date-format, or as YYYY-MM-DD
+const formatLogDate = (log: LogEntry, display: DisplayOptions): string => {
+  if (display.relativeDates) {
+    return log.relativeDate;
+  }
+  if (!display.dateFormat) {
+    return log.date;
+  }
+  return formatDateInZone(log.timestamp, display.timeZone, display.dateFormat);
+};
+
+// Column names accepted by --columns, in their default order
+const COLUMN_NAMES = [
+  "date",
+  "hash",
+  "message",
+  "author",
+  "email",
+  "origin",
+  "credit",
+  "signed",
+  "dco",
+  "files",
+  "insertions",
+  "deletions",
+  "paths",
+] as const;
+type ColumnName = (typeof COLUMN_NAMES)[number];
+
+// Parse --columns=a,b,c into known column names, keeping the given order
+const parseColumns = (args: string[]): ColumnName[] | undefined => {
+  const value = getFlagValue(args, "--columns");
+  if (value === undefined) {
+    return undefined;
+  }
+
+  const names = value
+    .split(",")
+    .map((name) => name.trim().toLowerCase())
+    .filter(Boolean);
+
+  if (names.length === 0) {
+    throw new WhoError(
+      "--columns expects a list of columns, e.g. --columns=hash,date,message"
+    );
+  }
+
+  const unknown = names.filter(
+    (name) => !(COLUMN_NAMES as readonly string[]).includes(name)
+  );
+  if (unknown.length) {
+    throw new WhoError(
+      `Unknown column ${unknown.join(", ")}, expected some of ${COLUMN_NAMES.join(", ")}.`
+    );
+  }
+
+  // Deciding who gets credit changes which commits match, so it stays opt-in
+  if (names.includes("credit") && !args.includes("--co-authors")) {
+    throw new WhoError("The credit column needs --co-authors.");
+  }
+
+  return names as ColumnName[];
+};
+
+// A column of the log output: header, cell value and optional table color
+interface LogColumn {
+  name: ColumnName;
+  header: string;
+  value: (log: LogEntry) => string;
+  color?: (text: string) => string;
+  href?: (log: LogEntry) => string | undefined;
+}
+
+// Columns shown for the current display options, in order
+const getLogColumns = (display: DisplayOptions): LogColumn[] => {
+  const columns: LogColumn[] = [
+    {
+      name: "date",
+      header: "Date",
+      value: (log) => formatLogDate(log, display),
+      color: paint(display.colors.date),
+    },
+    {
+      name: "hash",
+      header: "Hash",
+      value: (log) => log.commitHash,
+      href: (log) =>
+        display.commitUrl ? `${display.commitUrl}${log.commitHash}` : undefined,
+    },
+    {
+      name: "message",
+      header: "Message",
+      value: (log) => log.commitMessage,
+      color: display.colors.message ? paint(display.colors.message) : undefined,
+    },
+    { name: "author", header: "Author", value: (log) => log.authorName },
+  ];
+
+  if (display.showEmail) {
+    columns.push({
+      name: "email",
+      header: "Email",
+      value: (log) => log.authorEmail,
+    });
+  }
+
+  columns.push({
+    name: "origin",
+    header: "Origin",
+    value: (log) => log.origin ?? "",
+    color: display.colors.origin ? paint(display.colors.origin) : undefined,
+  });
+
+  if (display.showAttribution) {
+    columns.push({
+      name: "credit",
+      header: "Credit",
+      value: (log) => log.attribution ?? "",
+    });
+  }
+
+  if (display.showSignature) {
+    columns.push({
+      name: "signed",
+      header: "Signed",
+      value: (log) => formatSignature(log.signature),
+      color: (text) => SIGNATURE_COLORS[text](text),
+    });
+  }
+
+  if (display.showDco) {
+    columns.push({
+      name: "dco",
+      header: "DCO",
+      value: (log) => (log.signedOffBy?.length ? "✔" : "✘"),
+      color: (text) => SIGNATURE_COLORS[text](text),
+    });
+  }
+
+  if (display.showStat) {
+    columns.push(
+      {
+        name: "files",
+        header: "Files",
+        value: (log) => String(log.filesChanged ?? 0),
+      },
+      {
+        name: "insertions",
+        header: "+",
+        value: (log) => String(log.insertions ?? 0),
+        color: chalk.green,
+      },
+      {
+        name: "deletions",
+        header: "-",
+        value: (log) => String(log.deletions ?? 0),
+        color: chalk.red,
+      }
+    );
+  }
+
+  if (display.showPaths) {
+    columns.push({
+      name: "paths",
+      header: "Paths",
+      value: (log) => pluralize(log.files?.length ?? 0, "file"),
+    });
+  }
+
+  const selected = display.columns
+    ? display.columns.flatMap(
+        (name) => columns.find((column) => column.name === name) ?? []
+      )
+    : columns;
+
+  return display.hideOrigin
+    ? selected.filter((column) => column.name !== "origin")
+    : selected;
+};
+
+// Paths listed per commit by --files before the rest are summarized
+const MAX_LISTED_PATHS = 5;
+
+// List a commit's changed files one per line, indented to sit under the
+// commit and ending with "+N more" when there are too many to list
+const formatPaths = (files: string[] = []): string =>
+  [
+    ...files.slice(0, MAX_LISTED_PATHS),
+    ...(files.length > MAX_LISTED_PATHS
+      ? [`+${files.length - MAX_LISTED_PATHS} more`]
+      : []),
+  ]
+    .map((path) => `    ${path}`)
+    .join("\n");
+
+// Colors for the Signed column symbols
+const SIGNATURE_COLORS: Record<string, (text: string) => string> = {
+  "✔": chalk.green,
+  "✘": chalk.red,
+  "?": chalk.yellow,
+};
+
+// Show a %G? signature status as ✔ (good), ✘ (bad or unsigned) or ? (anything
+// git could not fully verify, such as a missing or expired key)
+const formatSignature = (status: string = "N"): string => {
+  if (status === "G") {
+    return "✔";
+  }
+  return status === "B" || status === "R" || status === "N" ? "✘" : "?";
+};
+
+// Display log entries in a formatted table
+const displayLogsTable = (
+  author: string,
+  scope: string,
+  logs: LogEntry[],
+  display: DisplayOptions
+): void => {
+  if (logs.length === 0) {
+    console.log(`\nNo logs found for ${author} ${scope}.`);
+    return;
+  }
+
+  const fixups = logs.filter((log) => isFixupCommit(log.commitMessage)).length;
+  const fixupNote = `${pluralize(fixups, "fixup/squash commit")} to squash.`;
+  const footer =
+    (display.showSummary ? `\n${summarizeLogs(logs)}` : "") +
+    (display.workHours
+      ? `\n${summarizeWorkHours(logs, display.workHours, display)}`
+      : "") +
+    (display.flagFixups && fixups ? `\n${chalk.yellow(fixupNote)}` : "");
+
+  if (!display.groupBy) {
+    const table = renderLogsTable(logs, display);
+    printPaged(`\nRecent logs for ${author}:\n${table}${footer}`, display);
+    return;
+  }
+
+  const sections = groupLogs(logs, display.groupBy, display.weekStart).map(
+    ([label, group]) =>
+      `\n${chalk.bold(label)} (${pluralize(group.length, "commit")})\n` +
+      renderLogsTable(group, display)
+  );
+
+  printPaged(
+    `\nRecent logs for ${author}:\n${sections.join("\n")}${footer}`,
+    display
+  );
+};
+
+// Summarize a set of logs in one line: commits, line changes, files, dates
+const summarizeLogs = (logs: LogEntry[]): string => {
+  const dates = logs.map((log) => log.date).sort();
+  const files = new Set(logs.flatMap((log) => log.files ?? []));
+  const insertions = logs.reduce((sum, log) => sum + (log.insertions ?? 0), 0);
+  const deletions = logs.reduce((sum, log) => sum + (log.deletions ?? 0), 0);
+  const span =
+    dates[0] === dates[dates.length - 1]
+      ? `on ${dates[0]}`
+      : `from ${dates[0]} to ${dates[dates.length - 1]}`;
+
+  return [
+    chalk.bold("Summary:"),
+    `${pluralize(logs.length, "commit")},`,
+    `${chalk.green(`+${insertions}`)} ${chalk.red(`-${deletions}`)},`,
+    `${pluralize(files.size, "file")} touched ${span}`,
+  ].join(" ");
+};
+
+// Working day assumed by a bare --work-hours: 09:00 to 17:00
+const DEFAULT_WORK_HOURS: [number, number] = [9, 17];
+
+// Parse --work-hours[=start-end] into the hour the working day starts and the
+// hour it ends, leaving the analysis off when the flag is not given
+const parseWorkHours = (args: string[]): [number, number] | undefined => {
+  const value = getFlagValue(args, "--work-hours");
+  if (value === undefined) {
+    return args.includes("--work-hours") ? DEFAULT_WORK_HOURS : undefined;
+  }
+
+  const [, start, end] = value.match(/^(\d{1,2})-(\d{1,2})$/) ?? [];
+  if (start === undefined || Number(start) >= Number(end) || Number(end) > 24) {
+    throw new WhoError(
+      `--work-hours expects a range of hours such as 9-17, got "${value}".`
+    );
+  }
+
+  return [Number(start), Number(end)];
+};
+
+// Count commits made on weekdays within working hours, on weekdays outside
+// them and at weekends. Times are read in each commit's own UTC offset, so
+// they reflect the committer's local clock rather than the viewer's
+const summarizeWorkHours = (
+  logs: LogEntry[],
+  [start, end]: [number, number],
+  display: DisplayOptions
+): string => {
+  const counts = [0, 0, 0];
+
+  logs.forEach((log) => {
+    const [year, month, day, hour] = (
+      log.timestamp.match(/^(\d{4})-(\d{2})-(\d{2})T(\d{2})/) ?? []
+    )
+      .slice(1)
+      .map(Number);
+    const weekday = new Date(Date.UTC(year, month - 1, day)).getUTCDay();
+
+    if (weekday === 0 || weekday === 6) {
+      counts[2] += 1;
+    } else {
+      counts[hour >= start && hour < end ? 0 : 1] += 1;
+    }
+  });
+
+  const hours = [start, end]
+    .map((value) => `${String(value).padStart(2, "0")}:00`)
+    .join("-");
+  const shares = getPercentages(counts);
+  const table = createTable(["When", "Commits", "Share"], display);
+  [`Weekdays ${hours}`, "Weekdays, other hours", "Weekends"].forEach(
+    (label, index) => {
+      table.push([
+        label,
+        chalk.yellow(String(counts[index])),
+        chalk.green(`${shares[index].toFixed(1)}%`),
+      ]);
+    }
+  );
+
+  return `${chalk.bold("Commit times:")}\n${table.toString()}`;
+};
+
+// Render log entries as a table string
+const renderLogsTable = (logs: LogEntry[], display: DisplayOptions): string => {
+  const columns = getLogColumns(display);
+  const table = createTable(
+    columns.map((column) => column.header),
+    display,
+    getColumnWidths(columns, logs, display.maxMessageWidth)
+  );
+
+  // With several authors, cells without their own color take the author's
+  const multiAuthor = new Set(logs.map((log) => log.authorName)).size > 1;
+
+  logs.forEach((log) => {
+    // Flagged fixups are shown entirely in the warning color
+    if (display.flagFixups && isFixupCommit(log.commitMessage)) {
+      table.push(columns.map((column) => chalk.yellow(column.value(log))));
+      return;
+    }
+
+    const tint = multiAuthor ? chalk[getAuthorColor(log.authorName)] : null;
+    table.push(
+      columns.map((column) => {
+        const text = column.value(log);
+        const content = column.color
+          ? column.color(text)
+          : tint
+            ? tint(text)
+            : text;
+        const href = column.href?.(log);
+        // cli-table3 wraps cells with an href in an OSC 8 hyperlink
+        return href ? { content, href } : content;
+      })
+    );
+  });
+
+  return table.toString();
+};
+
+// Terminals known to render OSC 8 hyperlinks, by $TERM_PROGRAM
+const HYPERLINK_TERMINALS = [
+  "iTerm.app",
+  "WezTerm",
+  "vscode",
+  "ghostty",
+  "Hyper",
+];
+
+// Check whether the terminal on stdout can show clickable links.
+// FORCE_HYPERLINK=1 or 0 overrides the guess
+const supportsHyperlinks = (): boolean => {
+  const forced = process.env.FORCE_HYPERLINK;
+  if (forced !== undefined && forced !== "") {
+    return forced !== "0";
+  }
+  if (!process.stdout.isTTY) {
+    return false;
+  }
+
+  const { TERM, TERM_PROGRAM, VTE_VERSION, WT_SESSION, KITTY_WINDOW_ID } =
+    process.env;
+  return (
+    HYPERLINK_TERMINALS.includes(TERM_PROGRAM ?? "") ||
+    Boolean(WT_SESSION || KITTY_WINDOW_ID) ||
+    TERM === "xterm-kitty" ||
+    Number(VTE_VERSION) >= 5000
+  );
+};
+
+// Turn the origin remote into the web URL commit hashes are appended to, for
+// GitHub and GitLab remotes in either the SSH or HTTPS form
+const getCommitUrlBase = (): string | undefined => {
+  let remote: string;
+  try {
+    remote = execFileSync("git", ["remote", "get-url", "origin"], {
+      encoding: "utf8",
+      stdio: ["ignore", "pipe", "ignore"],
+    }).trim();
+  } catch {
+    return undefined;
+  }
+
+  const [, host, path] =
+    remote.match(
+      /^(?:[\w+]+:\/\/)?(?:[^@/]+@)?([^/:]+)(?::\d+)?[:/](.+?)(?:\.git)?\/?$/
+    ) ?? [];
+
+  if (host?.includes("github")) {
+    return `https://${host}/${path}/commit/`;
+  }
+  if (host?.includes("gitlab")) {
+    return `https://${host}/${path}/-/commit/`;
+  }
+  return undefined;
+};
+
+// Colors handed out to authors in multi-author tables, leaving out the
+// default date, header and border colors
+const AUTHOR_COLORS: ForegroundColorName[] = [
+  "cyanBright",
+  "magenta",
+  "green",
+  "blueBright",
+  "red",
+  "magentaBright",
+  "greenBright",
+  "blue",
+  "redBright",
+  "white",
+];
+
+// Pick a color for an author from a hash of the name, so the same person
+// always gets the same color across runs
+const getAuthorColor = (name: string): ForegroundColorName => {
+  let hash = 0;
+  for (const char of name) {
+    hash = (hash * 31 + char.charCodeAt(0)) >>> 0;
+  }
+  return AUTHOR_COLORS[hash % AUTHOR_COLORS.length];
+};
+
+// Periods accepted by --group-by
+const GROUP_PERIODS = ["day", "week", "month"] as const;
+type GroupPeriod = (typeof GROUP_PERIODS)[number];
+
+// Parse the --group-by value, leaving the table ungrouped when it is not given
+const parseGroupBy = (value: string | undefined): GroupPeriod | undefined => {
+  if (value === undefined) {
+    return undefined;
+  }
+
+  if (!(GROUP_PERIODS as readonly string[]).includes(value)) {
+    throw new WhoError(
+      `--group-by expects one of ${GROUP_PERIODS.join(", ")}, got "${value}".`
+    );
+  }
+
+  return value as GroupPeriod;
+};
+
+// Days a week can start on with --week-start; Monday is the ISO 8601 default
+const WEEK_STARTS = ["monday", "sunday"] as const;
+type WeekStart = (typeof WEEK_STARTS)[number];
+
+// Parse the --week-start value, defaulting to Monday
+const parseWeekStart = (value: string | undefined): WeekStart => {
+  if (value === undefined) {
+    return "monday";
+  }
+
+  if (!(WEEK_STARTS as readonly string[]).includes(value)) {
+    throw new WhoError(
+      `--week-start expects one of ${WEEK_STARTS.join(", ")}, got "${value}".`
+    );
+  }
+
+  return value as WeekStart;
+};
+
+// Find midnight on the first day of the week that a date falls in
+const getStartOfWeek = (date: Date, weekStart: WeekStart): Date => {
+  const start = new Date(date.getFullYear(), date.getMonth(), date.getDate());
+  const day = start.getDay();
+  const offset = weekStart === "monday" ? (day + 6) % 7 : day;
+  start.setDate(start.getDate() - offset);
+  return start;
+};
+
+// Label the day, week or month a YYYY-MM-DD date falls in
+const getPeriodLabel = (
+  date: string,
+  period: GroupPeriod,
+  weekStart: WeekStart
+): string => {
+  const [year, month, day] = date.split("-").map(Number);
+
+  if (period === "month") {
+    return `${MONTH_LABELS[month - 1]} ${year}`;
+  }
+
+  if (period === "week") {
+    const start = getStartOfWeek(new Date(year, month - 1, day), weekStart);
+    return `Week of ${formatShortDate(start)}`;
+  }
+
+  return date;
+};
+
+// Split logs into groups by period, in the order each period first appears
+const groupLogs = (
+  logs: LogEntry[],
+  period: GroupPeriod,
+  weekStart: WeekStart = "monday"
+): [string, LogEntry[]][] => {
+  const groups = new Map<string, LogEntry[]>();
+
+  logs.forEach((log) => {
+    const label = getPeriodLabel(log.date, period, weekStart);
+    groups.set(label, [...(groups.get(label) ?? []), log]);
+  });
+
+  return [...groups];
+};
+
+// Print text, piping it through $PAGER (or less -R) when it would not fit on
+// the terminal
+const printPaged = (text: string, display: DisplayOptions): void => {
+  const height = process.stdout.rows ?? 0;
+  const lines = text.split("\n").length;
+
+  if (
+    display.outputPath ||
+    !display.pager ||
+    !process.stdout.isTTY ||
+    !height ||
+    lines < height
+  ) {
+    writeOutput(text, display);
+    return;
+  }
+
+  const pager = process.env.PAGER || "less -R";
+  const result = spawnSync(pager, {
+    input: `${text}\n`,
+    stdio: ["pipe", "inherit", "inherit"],
+    shell: true,
+  });
+
+  if (result.error || result.status === 127) {
+    console.log(text);
+  }
+};
+
+// The --output file, emptied on the first write rather than at startup so a
+// command rejected by its flag checks leaves an existing file alone
+let outputFile: { path: string; prepared: boolean } | undefined;
+
+// Print rendered output on stdout, or append it to the --output file
+const writeOutput = (text: string, display: DisplayOptions): void => {
+  if (!display.outputPath) {
+    console.log(text);
+    return;
+  }
+
+  if (outputFile && !outputFile.prepared) {
+    prepareOutputFile(outputFile.path);
+    outputFile.prepared = true;
+  }
+
+  try {
+    appendFileSync(display.outputPath, `${text}\n`);
+  } catch (error) {
+    throw new WhoError("Could not write output", { cause: error });
+  }
+};
+
+// Create (or empty) the --output file, making any missing parent directories
+const prepareOutputFile = (path: string): void => {
+  try {
+    mkdirSync(dirname(path), { recursive: true });
+    writeFileSync(path, "");
+  } catch (error) {
+    throw new WhoError(`Could not write to ${path}`, { cause: error });
+  }
+};
+
+// Print log entries as a JSON array
+const displayLogsJson = (logs: LogEntry[], display: DisplayOptions): void => {
+  writeOutput(JSON.stringify(logs, null, 2), display);
+};
+
+// Stream log entries as JSON Lines, one compact object per commit, returning
+// how many were written
+const displayLogsNdjson = (
+  authors: string[],
+  timeRange: string,
+  fetchOptions: FetchOptions,
+  display: DisplayOptions
+): Promise<number> =>
+  streamLogsForAuthor(authors, timeRange, fetchOptions, (entry) =>
+    writeOutput(JSON.stringify(entry), display)
+  );
+
+// Escape characters that would break a GitHub-flavored Markdown table cell
+const escapeMarkdownCell = (cell: string): string =>
+  cell
+    .replace(/\\/g, "\\\\")
+    .replace(/\|/g, "\\|")
+    .replace(/\n/g, "<br>");
+
+// Fields available to --format templates, named like Go's text/template
+const TEMPLATE_FIELDS: Record<
+  string,
+  (log: LogEntry, display: DisplayOptions) => string
+> = {
+  CommitHash: (log) => log.commitHash,
+  CommitMessage: (log) => log.commitMessage,
+  Origin: (log) => log.origin ?? "",
+  Date: (log, display) => formatLogDate(log, display),
+  Timestamp: (log) => log.timestamp,
+  AuthorName: (log) => log.authorName,
+  AuthorEmail: (log) => log.authorEmail,
+  Body: (log) => log.body ?? "",
+};
+
+// Matches a {{.Field}} placeholder, allowing spaces inside the braces
+const TEMPLATE_PLACEHOLDER = /\{\{\s*\.(\w+)\s*\}\}/g;
+
+// Read --format=<template>, rejecting empty templates and unknown fields
+const parseFormatTemplate = (args: string[]): string | undefined => {
+  const template = getFlagValue(args, "--format");
+  if (template === undefined && !args.includes("--format")) {
+    return undefined;
+  }
+
+  if (!template) {
+    throw new WhoError(
+      "--format expects a template, e.g. --format='{{.CommitHash}} {{.CommitMessage}}'"
+    );
+  }
+
+  const fields = Object.keys(TEMPLATE_FIELDS);
+  for (const [, field] of template.matchAll(TEMPLATE_PLACEHOLDER)) {
+    if (!fields.includes(field)) {
+      const expected = fields.map((name) => `.${name}`).join(", ");
+      throw new WhoError(
+        `Unknown --format field .${field}, expected one of ${expected}.`
+      );
+    }
+  }
+
+  return template;
+};
+
+// Print one line per log entry by filling in the --format template
+const displayLogsTemplate = (
+  logs: LogEntry[],
+  template: string,
+  display: DisplayOptions
+): void => {
+  if (logs.length === 0) {
+    return;
+  }
+
+  const lines = logs.map((log) => {
+    const line = template.replace(TEMPLATE_PLACEHOLDER, (_, field: string) =>
+      TEMPLATE_FIELDS[field](log, display)
+    );
+    // With --body the message body is printed indented under each line, and
+    // with --files the changed files after it
+    return [
+      line,
+      ...(log.body ? [log.body.replace(/^(?=.)/gm, "    ")] : []),
+      ...(display.showPaths && log.files?.length
+        ? [formatPaths(log.files)]
+        : []),
+    ].join("\n");
+  });
+
+  writeOutput(lines.join("\n"), display);
+};
+
+// Replace tabs and line breaks so a value stays inside its TSV field
+const toTsvField = (value: string): string => value.replace(/[\t\r\n]+/g, " ");
+
+// Print log entries as headerless tab-separated lines for cut and awk:
+// hash, date, author, message and origin
+const displayLogsTsv = (logs: LogEntry[], display: DisplayOptions): void => {
+  if (logs.length === 0) {
+    return;
+  }
+
+  const lines = logs.map((log) =>
+    [
+      log.commitHash,
+      formatLogDate(log, display),
+      log.authorName,
+      log.commitMessage,
+      log.origin ?? "",
+    ]
+      .map(toTsvField)
+      .join("\t")
+  );
+
+  writeOutput(lines.join("\n"), display);
+};
+
+// Print log entries as a GitHub-flavored Markdown table
+const displayLogsMarkdown = (
+  logs: LogEntry[],
+  display: DisplayOptions
+): void => {
+  const columns = getLogColumns(display);
+  const toRow = (cells: string[]): string =>
+    `| ${cells.map(escapeMarkdownCell).join(" | ")} |`;
+
+  const lines = [
+    toRow(columns.map((column) => column.header)),
+    `| ${columns.map(() => "---").join(" | ")} |`,
+    ...logs.map((log) => toRow(columns.map((column) => column.value(log)))),
+  ];
+
+  writeOutput(lines.join("\n"), display);
+};
+
+// Quote a CSV field if it contains a delimiter, quote or newline
+const escapeCsvField = (field: string): string => {
+  if (/[",\r\n]/.test(field)) {
+    return `"${field.replace(/"/g, '""')}"`;
+  }
+  return field;
+};
+
+// Write log entries as CSV to the output, or to a file when a path is given
+const displayLogsCsv = (
+  logs: LogEntry[],
+  display: DisplayOptions,
+  csvPath?: string
+): void => {
+  const rows = [
+    ["Commit Hash", "Commit Message", "Origin"],
+    ...logs.map((log) => [log.commitHash, log.commitMessage, log.origin ?? ""]),
+  ];
+  const csv =
+    rows.map((row) => row.map(escapeCsvField).join(",")).join("\n") + "\n";
+
+  if (!csvPath) {
+    writeOutput(csv.trimEnd(), display);
+    return;
+  }
+
+  try {
+    writeFileSync(csvPath, csv);
+    console.log(`Wrote ${logs.length} rows to ${csvPath}`);
+  } catch (error) {
+    throw new WhoError("Could not write CSV", { cause: error });
+  }
+};
+
+// Type for time range selection
+interface TimeRangeSelection {
+  selectedTimeRange: string;
+}
+
+// Type for a typed-in time range
+interface CustomTimeRange {
+  customTimeRange: string;
+}
+
+// Type for author selection
+interface AuthorSelection {
+  selectedAuthors: string[];
+}
+
+// Type for the contributor filter
+interface AuthorFilter {
+  filter: string;
+}
+
+// Ask for a filter before showing the picker once the list gets this long
+const FILTER_THRESHOLD = 20;
+
+// Case-insensitive fuzzy match: every query character appears in order
+const fuzzyMatch = (query: string, name: string): boolean => {
+  const target = name.toLowerCase();
+  let position = 0;
+
+  for (const char of query.toLowerCase()) {
+    position = target.indexOf(char, position);
+    if (position === -1) {
+      return false;
+    }
+    position++;
+  }
+
+  return true;
+};
+
+// Prompts can only be answered from a terminal, unless --non-interactive or
+// the CI variable set by most CI systems rules them out
+const canPrompt = (args: string[]): boolean =>
+  process.stdin.isTTY === true &&
+  !args.includes("--non-interactive") &&
+  ["", "0", "false"].includes(process.env.CI ?? "");
+
+// Fail instead of waiting forever on a prompt no one can answer
+const requirePrompt = (args: string[], feature: string, hint: string): void => {
+  if (!canPrompt(args)) {
+    throw new WhoError(
+      `${feature} needs to prompt, but git who is not running interactively (no terminal, --non-interactive or CI is set).`,
+      { hint }
+    );
+  }
+};
+
+// Prompt the user to pick one or more authors from the contributors
+const selectAuthors = async (
+  refresh: boolean = false,
+  all: boolean = false,
+  ignoreMailmap: boolean = false
+): Promise<string[]> => {
+  const spinner = startSpinner("Fetching contributors...");
+  const contributors = fetchContributors(refresh, all, ignoreMailmap);
+  spinner.succeed("Contributors fetched!");
+
+  let choices = contributors;
+
+  if (contributors.length > FILTER_THRESHOLD) {
+    const { filter } = await inquirer.prompt<AuthorFilter>([
+      {
+        type: "input",
+        name: "filter",
+        message: `Filter ${contributors.length} contributors (leave empty to show all):`,
+      },
+    ]);
+
+    const matches = contributors.filter((name) => fuzzyMatch(filter, name));
+    if (matches.length > 0) {
+      choices = matches;
+    } else {
+      console.log(`No contributors match "${filter}", showing all.`);
+    }
+  }
+
+  const { selectedAuthors } = await inquirer.prompt<AuthorSelection>([
+    {
+      type: "checkbox",
+      name: "selectedAuthors",
+      message: "Select one or more authors to view logs for:",
+      choices,
+    },
+  ]);
+
+  if (selectedAuthors.length === 0) {
+    throw new WhoError("No author selected.");
+  }
+
+  return selectedAuthors;
+};
+
+// Picker value that asks for a free-form time range instead
+const OTHER_TIME_RANGE = "__other__";
+
+// Prompt the user to pick a preset time range or type their own
+const selectTimeRange = async (): Promise<string> => {
+  const { selectedTimeRange } = await inquirer.prompt<TimeRangeSelection>([
+    {
+      type: "list",
+      name: "selectedTimeRange",
+      message: "Select a time range for the logs:",
+      choices: [
+        "1 day ago",
+        "this week",
+        "1 week ago",
+        "2 weeks ago",
+        "1 month ago",
+        "3 months ago",
+        "6 months ago",
+        { name: "Other (type a value)", value: OTHER_TIME_RANGE },
+      ],
+    },
+  ]);
+
+  if (selectedTimeRange !== OTHER_TIME_RANGE) {
+    return selectedTimeRange;
+  }
+
+  const { customTimeRange } = await inquirer.prompt<CustomTimeRange>([
+    {
+      type: "input",
+      name: "customTimeRange",
+      message: 'Enter a time range (e.g. "3 days ago", "last monday"):',
+      validate: (value: string) =>
+        isValidTimeRange(value) ||
+        "git does not understand that time range, try another one.",
+    },
+  ]);
+  return customTimeRange.trim();
+};
+
+// Check whether git understands a time range, without exiting. git log never
+// rejects a --since value (words it cannot parse resolve to the current
+// time), so the range is read as an expiry date instead, which git parses
+// strictly and which works outside a repository too. That parser refuses
+// "today", which --since reads as now
+const isValidTimeRange = (timeRange: string): boolean => {
+  if (timeRange.trim() === "") {
+    return false;
+  }
+
+  if (/^\s*today\s*$/i.test(timeRange)) {
+    return true;
+  }
+
+  try {
+    execFileSync(
+      "git",
+      [
+        "-c",
+        `who.since=${timeRange}`,
+        "config",
+        "--type=expiry-date",
+        "who.since",
+      ],
+      { stdio: "ignore" }
+    );
+    return true;
+  } catch (error) {
+    return false;
+  }
+};
+
+// Make sure git accepts a user supplied time range before using it
+const validateTimeRange = (timeRange: string, flag: string = "--T"): void => {
+  if (timeRange.trim() === "") {
+    throw new WhoError(
+      `${flag} requires a time range, e.g. ${flag}="3 days ago"`
+    );
+  }
+
+  if (!isValidTimeRange(timeRange)) {
+    throw new WhoError(
+      `git does not understand the time range "${timeRange}".`,
+      { hint: 'Try e.g. "3 days ago", "last monday" or "2024-01-31".' }
+    );
+  }
+};
+
+// Make sure a tag exists, listing the available ones if it does not
+const validateTag = (tag: string, flag: string): void => {
+  try {
+    execFileSync(
+      "git",
+      ["rev-parse", "--verify", "--quiet", `refs/tags/${tag}^{commit}`],
+      { stdio: "ignore" }
+    );
+  } catch (error) {
+    const tags = execSync("git tag --list").toString().trim();
+
+    throw new WhoError(`${flag} tag "${tag}" does not exist.`, {
+      hint: tags
+        ? `Available tags:\n  ${tags.split("\n").join("\n  ")}`
+        : "This repository has no tags.",
+    });
+  }
+};
+
+// Build the revision range for --since-tag/--until-tag, if either is given
+const resolveTagRange = (
+  args: string[],
+  branch?: string
+): string | undefined => {
+  const sinceTag = getFlagValue(args, "--since-tag");
+  const untilTag = getFlagValue(args, "--until-tag");
+
+  if (sinceTag === undefined && untilTag === undefined) {
+    return undefined;
+  }
+
+  if (sinceTag !== undefined) {
+    validateTag(sinceTag, "--since-tag");
+  }
+  if (untilTag !== undefined) {
+    validateTag(untilTag, "--until-tag");
+  }
+
+  const end = untilTag ?? branch ?? "HEAD";
+  return sinceTag === undefined ? end : `${sinceTag}..${end}`;
+};
+
+// Build the revision range for --since-branch-point=<base>: the commits made
+// on the branch (or HEAD) since it forked from base
+const resolveBranchPointRange = (
+  args: string[],
+  branch?: string
+): string | undefined => {
+  const base = getFlagValue(args, "--since-branch-point");
+  if (base === undefined) {
+    return undefined;
+  }
+
+  if (base.trim() === "") {
+    throw new WhoError(
+      "--since-branch-point requires a base branch, e.g. --since-branch-point=main"
+    );
+  }
+
+  validateBranch(base);
+  const end = branch ?? "HEAD";
+
+  try {
+    const mergeBase = execFileSync("git", ["merge-base", base, end], {
+      encoding: "utf8",
+    }).trim();
+    const short = execFileSync("git", ["rev-parse", "--short", mergeBase], {
+      encoding: "utf8",
+    }).trim();
+    return `${short}..${end}`;
+  } catch {
+    throw new WhoError(
+      `${end} and ${base} have no common history to start from.`
+    );
+  }
+};
+
+// Make sure a branch exists, listing the available ones if it does not
+const validateBranch = (branch: string): void => {
+  try {
+    execFileSync(
+      "git",
+      ["rev-parse", "--verify", "--quiet", `${branch}^{commit}`],
+      { stdio: "ignore" }
+    );
+  } catch (error) {
+    const branches = execSync('git branch -a --format="%(refname:short)"')
+      .toString()
+      .trim()
+      .split("\n")
+      .filter((name) => name !== "");
+
+    throw new WhoError(`Branch "${branch}" does not exist.`, {
+      hint: `Available branches:\n  ${branches.join("\n  ")}`,
+    });
+  }
+};
+
+// Number of commits shown when --limit is not given
+const DEFAULT_LIMIT = 50;
+
+// Parse the --limit / -n value, where 0 means unlimited
+const parseLimit = (value: string | undefined): number => {
+  if (value === undefined) {
+    return DEFAULT_LIMIT;
+  }
+
+  if (!/^-?\d+$/.test(value.trim())) {
+    throw new WhoError(`--limit expects a whole number, got "${value}".`);
+  }
+
+  const limit = Number(value);
+  if (limit < 0) {
+    throw new WhoError("--limit cannot be negative (use 0 for no limit).");
+  }
+
+  return limit;
+};
+
+// Parse --max-message-width, where 0 shows full messages
+const parseMaxMessageWidth = (
+  value: string | undefined
+): number | undefined => {
+  if (value === undefined) {
+    return undefined;
+  }
+
+  if (!/^\d+$/.test(value.trim())) {
+    throw new WhoError(
+      `--max-message-width expects a whole number (0 for no limit), got "${value}".`
+    );
+  }
+
+  return Number(value);
+};
+
+// Parse the --tz value, where "local" (the default) means the system zone
+const parseTimeZone = (value: string | undefined): string | undefined => {
+  if (value === undefined || value.toLowerCase() === "local") {
+    return undefined;
+  }
+
+  try {
+    new Intl.DateTimeFormat("en-US", { timeZone: value });
+  } catch {
+    throw new WhoError(
+      `--tz expects a time zone such as UTC, Local or Europe/Berlin, got "${value}".`
+    );
+  }
+
+  return value;
+};
+
+// Default time range when --T is not given
+const DEFAULT_TIME_RANGE = "1 week ago";
+
+// Turn "this week" into the date the current week started, since git would
+// read it as seven days ago; other ranges are left for git to parse
+const expandTimeRange = (timeRange: string, weekStart: WeekStart): string =>
+  timeRange.trim().toLowerCase() === "this week"
+    ? `${formatShortDate(getStartOfWeek(new Date(), weekStart))} 00:00`
+    : timeRange;
+
+// Work out the time range from --T=<value>, the --T picker or the default
+const resolveTimeRange = async (
+  args: string[],
+  defaultTimeRange: string = DEFAULT_TIME_RANGE
+): Promise<string> => {
+  const timeValue = getFlagValue(args, "--T");
+  const weekStart = parseWeekStart(getFlagValue(args, "--week-start"));
+
+  if (timeValue !== undefined) {
+    // Pass a --T=<value> time range straight through to git
+    const timeRange = expandTimeRange(timeValue, weekStart);
+    validateTimeRange(timeRange);
+    return timeRange;
+  }
+
+  if (args.includes("--T") && !canPrompt(args)) {
+    console.warn(
+      chalk.yellow(
+        `Warning: cannot prompt for a time range here, using "${defaultTimeRange}". Pass --T=<range> to choose one.`
+      )
+    );
+  } else if (args.includes("--T")) {
+    // Prompt user for time range if --T is passed without a value
+    return expandTimeRange(await selectTimeRange(), weekStart);
+  }
+
+  // Only the config file passes a default of its own, so a bad one is
+  // reported there rather than as an empty result
+  const timeRange = expandTimeRange(defaultTimeRange, weekStart);
+  if (!isValidTimeRange(timeRange)) {
+    throw new WhoError(
+      `timeRange "${defaultTimeRange}" in ${CONFIG_PATH} is not a time range git understands.`,
+      { hint: 'Fix it, e.g. git who config set timeRange "2 weeks ago"' }
+    );
+  }
+  return timeRange;
+};
+
+// Colors used for the table header and border and the date, message and
+// origin columns; a part without a color keeps the terminal's default
+interface TableColors {
+  head?: ForegroundColorName;
+  border?: ForegroundColorName;
+  date?: ForegroundColorName;
+  message?: ForegroundColorName;
+  origin?: ForegroundColorName;
+}
+
+// Color schemes selectable with --theme; add an entry to offer a new one.
+// Only the basic color names are used, since cli-table3 colors the header and
+// border itself and does not know chalk's bright variants
+const THEMES: Record<string, TableColors> = {
+  default: { head: "cyan", border: "gray", date: "yellow" },
+  dracula: {
+    head: "magenta",
+    border: "gray",
+    date: "cyan",
+    message: "white",
+    origin: "green",
+  },
+  solarized: { head: "blue", border: "gray", date: "yellow", origin: "cyan" },
+  mono: {},
+};
+
+// Colors a config key can take: cli-table3 draws the header and border itself
+// and ignores chalk's bright variants there, so those keys get the basic names
+const getConfigColors = (key: string): readonly string[] =>
+  key === "dateColor"
+    ? foregroundColorNames
+    : foregroundColorNames.filter((color) => !color.endsWith("Bright"));
+
+// Theme used when neither --theme nor the config file picks one
+const DEFAULT_THEME = "default";
+
+// Look up the --theme (or config) theme by name
+const parseTheme = (value: string = DEFAULT_THEME): TableColors => {
+  if (!Object.hasOwn(THEMES, value)) {
+    throw new WhoError(
+      `--theme expects one of ${Object.keys(THEMES).join(", ")}, got "${value}".`
+    );
+  }
+
+  return THEMES[value];
+};
+
+// Color text with an optional color, leaving it as is without one
+const paint =
+  (color?: ForegroundColorName) =>
+  (text: string): string =>
+    color ? chalk[color](text) : text;
+
+// User defaults read from the config file; command-line flags override them
+interface WhoConfig {
+  contributor?: string;
+  timeRange?: string;
+  limit?: string;
+  theme?: string;
+  headColor?: ForegroundColorName;
+  borderColor?: ForegroundColorName;
+  dateColor?: ForegroundColorName;
+}
+
+const CONFIG_PATH = join(
+  process.env.XDG_CONFIG_HOME || join(homedir(), ".config"),
+  "git-addons",
+  "config.yaml"
+);
+
+const CONFIG_KEYS = [
+  "contributor",
+  "timeRange",
+  "limit",
+  "theme",
+  "headColor",
+  "borderColor",
+  "dateColor",
+];
+
+// Load the flat `key: value` config file, ignoring it if it does not exist
+const loadConfig = (): WhoConfig => {
+  if (!existsSync(CONFIG_PATH)) {
+    return {};
+  }
+
+  const config: Record<string, string> = {};
+
+  readFileSync(CONFIG_PATH, "utf-8")
+    .split("\n")
+    .forEach((rawLine, index) => {
+      const line = rawLine.replace(/\s+#.*$/, "").trim();
+      if (line === "" || line.startsWith("#")) {
+        return;
+      }
+
+      const match = line.match(/^([A-Za-z]+)\s*:\s*(.*)$/);
+      if (!match || !CONFIG_KEYS.includes(match[1])) {
+        console.warn(
+          chalk.yellow(
+            `Warning: ignoring line ${index + 1} of ${CONFIG_PATH}: ${rawLine}`
+          )
+        );
+        return;
+      }
+
+      const [, key, value] = match;
+      config[key] = value.replace(/^(["'])(.*)\1$/, "$2");
+    });
+
+  for (const key of ["headColor", "borderColor", "dateColor"]) {
+    const color = config[key];
+    if (color !== undefined && !getConfigColors(key).includes(color)) {
+      console.warn(
+        chalk.yellow(
+          `Warning: ignoring unsupported ${key} "${color}" in config.`
+        )
+      );
+      delete config[key];
+    }
+  }
+
+  if (config.theme !== undefined && !Object.hasOwn(THEMES, config.theme)) {
+    console.warn(
+      chalk.yellow(
+        `Warning: ignoring unknown theme "${config.theme}" in config.`
+      )
+    );
+    delete config.theme;
+  }
+
+  return config as WhoConfig;
+};
+
+// Check a value before `git who config set` stores it, so the file only holds
+// values the loader accepts
+const validateConfigValue = (key: string, value: string): void => {
+  if (value.trim() === "") {
+    throw new WhoError(`${key} cannot be empty.`);
+  }
+
+  // The loader strips everything after " #" as a comment
+  if (/\s#/.test(value)) {
+    throw new WhoError(`${key} cannot contain " #", which starts a comment.`);
+  }
+
+  if (key === "limit") {
+    parseLimit(value);
+  } else if (key === "contributor") {
+    validateAuthor(value);
+  } else if (key === "timeRange") {
+    if (!isValidTimeRange(expandTimeRange(value, parseWeekStart(undefined)))) {
+      throw new WhoError(
+        `git does not understand the time range "${value}".`,
+        { hint: 'Try e.g. "3 days ago", "last monday" or "2024-01-31".' }
+      );
+    }
+  } else if (key === "theme") {
+    parseTheme(value);
+  } else if (key.endsWith("Color") && !getConfigColors(key).includes(value)) {
+    throw new WhoError(`${key} does not support the color "${value}".`, {
+      hint: `Colors: ${getConfigColors(key).join(", ")}`,
+    });
+  }
+};
+
+// Set one key in the config file, replacing its line or appending one, and
+// leaving comments and the other keys as they are
+const saveConfigValue = (key: string, value: string): void => {
+  const lines = existsSync(CONFIG_PATH)
+    ? readFileSync(CONFIG_PATH, "utf-8").replace(/\n$/, "").split("\n")
+    : [];
+  const index = lines.findIndex((line) =>
+    new RegExp(`^\\s*${key}\\s*:`).test(line)
+  );
+
+  if (index === -1) {
+    lines.push(`${key}: ${value}`);
+  } else {
+    lines[index] = `${key}: ${value}`;
+  }
+
+  try {
+    mkdirSync(dirname(CONFIG_PATH), { recursive: true });
+    writeFileSync(CONFIG_PATH, `${lines.join("\n")}\n`);
+  } catch (error) {
+    throw new WhoError(`Could not write ${CONFIG_PATH}`, { cause: error });
+  }
+};
+
+// Print the settings in the config file as `key: value` lines
+const printConfig = (): void => {
+  const config = loadConfig() as Record<string, string | undefined>;
+  const lines = CONFIG_KEYS.filter((key) => config[key] !== undefined).map(
+    (key) => `${key}: ${config[key]}`
+  );
+
+  console.log(
+    lines.length ? lines.join("\n") : `No settings in ${CONFIG_PATH} yet.`
+  );
+};
+
+// Read, change or list the config file defaults:
+// git who config get <key> | set <key> <value> | list. The arguments are
+// taken as given, so a value such as "-3" is not mistaken for a flag
+const runConfig = (commandArgs: string[]): void => {
+  const [action, key, ...words] = commandArgs;
+  const usage = "Usage: git who config get <key> | set <key> <value> | list";
+
+  if (action === "list" || action === undefined) {
+    printConfig();
+    return;
+  }
+
+  if (action !== "get" && action !== "set") {
+    throw new WhoError(`Unknown config action "${action}".`, { hint: usage });
+  }
+
+  if (key === undefined || !CONFIG_KEYS.includes(key)) {
+    throw new WhoError(
+      key === undefined
+        ? `config ${action} needs a key.`
+        : `Unknown config key "${key}".`,
+      { hint: `Keys: ${CONFIG_KEYS.join(", ")}` }
+    );
+  }
+
+  if (action === "get") {
+    const value = (loadConfig() as Record<string, string | undefined>)[key];
+    // Like git config, an unset key prints nothing and exits with 1
+    if (value === undefined) {
+      process.exitCode = 1;
+      return;
+    }
+    console.log(value);
+    return;
+  }
+
+  // Unquoted values with spaces arrive as several words
+  const value = words.join(" ");
+  if (words.length === 0) {
+    throw new WhoError(`config set needs a value for ${key}.`, { hint: usage });
+  }
+
+  validateConfigValue(key, value);
+  saveConfigValue(key, value);
+  console.log(`Saved ${key} in ${CONFIG_PATH}:`);
+  printConfig();
+};
+
+// Output formats supported by git who
+type OutputFormat =
+  | "table"
+  | "json"
+  | "csv"
+  | "tsv"
+  | "markdown"
+  | "count"
+  | "ndjson"
+  | "template";
+
+// Pick the output format from the command-line flags
+const getOutputFormat = (args: string[]): OutputFormat => {
+  if (args.includes("--count-only")) {
+    return "count";
+  }
+  if (hasFlag(args, "--format")) {
+    return "template";
+  }
+  if (args.includes("--ndjson")) {
+    return "ndjson";
+  }
+  if (args.includes("--json") || args.includes("-j")) {
+    return "json";
+  }
+  if (hasFlag(args, "--csv")) {
+    return "csv";
+  }
+  if (args.includes("--tsv") || args.includes("--porcelain")) {
+    return "tsv";
+  }
+  if (args.includes("--markdown")) {
+    return "markdown";
+  }
+  return "table";
+};
+
+// Orders accepted by --sort; a leading "-" reverses the date order
+const SORT_KEYS = ["date", "-date", "message", "hash"] as const;
+type SortKey = (typeof SORT_KEYS)[number];
+
+// Parse the --sort value, leaving git's order when it is not given
+const parseSort = (value: string | undefined): SortKey | undefined => {
+  if (value === undefined) {
+    return undefined;
+  }
+
+  if (!(SORT_KEYS as readonly string[]).includes(value)) {
+    throw new WhoError(
+      `--sort expects one of ${SORT_KEYS.join(", ")}, got "${value}".`
+    );
+  }
+
+  return value as SortKey;
+};
+
+// Sort logs by the given key; the sort is stable so ties keep git's order
+const sortLogs = (logs: LogEntry[], sort?: SortKey): LogEntry[] => {
+  switch (sort) {
+    // The date column is day-only, so sort on the full commit time
+    case "date":
+      return [...logs].sort(
+        (a, b) => Date.parse(a.timestamp) - Date.parse(b.timestamp)
+      );
+    case "-date":
+      return [...logs].sort(
+        (a, b) => Date.parse(b.timestamp) - Date.parse(a.timestamp)
+      );
+    case "message":
+      return [...logs].sort((a, b) =>
+        a.commitMessage.localeCompare(b.commitMessage)
+      );
+    case "hash":
+      return [...logs].sort((a, b) => a.commitHash.localeCompare(b.commitHash));
+    default:
+      return logs;
+  }
+};
+
+// Output format selected on the command line
+interface DisplayOptions {
+  format: OutputFormat;
+  template?: string;
+  csvPath?: string;
+  outputPath?: string;
+  columns?: ColumnName[];
+  hideOrigin?: boolean;
+  commitUrl?: string;
+  showEmail?: boolean;
+  showStat?: boolean;
+  showPaths?: boolean;
+  showSignature?: boolean;
+  showDco?: boolean;
+  flagFixups?: boolean;
+  showAttribution?: boolean;
+  showSummary?: boolean;
+  workHours?: [number, number];
+  relativeDates?: boolean;
+  maxMessageWidth?: number;
+  dateFormat?: string;
+  timeZone?: string;
+  pager?: boolean;
+  sort?: SortKey;
+  groupBy?: GroupPeriod;
+  weekStart: WeekStart;
+  color: boolean;
+  colors: TableColors;
+}
+
+// Flags that can take their value as the following argument, e.g. `-n 10`
+const SEPARATE_VALUE_FLAGS = ["-n", "-o", "--repo", "--min-commits", "--max"];
+
+// Return the value of a `--flag=value` (or `-n value`) argument, if present
+const getFlagValue = (args: string[], flag: string): string | undefined => {
+  const arg = args.find((a) => a.startsWith(`${flag}=`));
+  if (arg !== undefined) {
+    return arg.slice(flag.length + 1);
+  }
+
+  if (SEPARATE_VALUE_FLAGS.includes(flag)) {
+    const index = args.indexOf(flag);
+    if (index !== -1) {
+      return args[index + 1] ?? "";
+    }
+  }
+
+  return undefined;
+};
+
+// Return every value of a repeatable `--flag=value` argument
+const getFlagValues = (args: string[], flag: string): string[] =>
+  args
+    .filter((a) => a.startsWith(`${flag}=`))
+    .map((a) => a.slice(flag.length + 1));
+
+// Return the arguments that are neither flags nor flag values
+const getPositionalArgs = (args: string[]): string[] =>
+  args.filter(
+    (arg, index) =>
+      !arg.startsWith("-") && !SEPARATE_VALUE_FLAGS.includes(args[index - 1])
+  );
+
+// Colors are disabled by --no-color or a non-empty NO_COLOR variable
+const isColorEnabled = (args: string[]): boolean =>
+  !args.includes("--no-color") && !process.env.NO_COLOR;
+
+// Check whether a flag was passed, either bare or as `--flag=value`
+const hasFlag = (args: string[], flag: string): boolean =>
+  args.includes(flag) || getFlagValue(args, flag) !== undefined;
+
+// Fetch and display logs in the requested format, returning the entries shown
+const showLogs = async (
+  authors: string[],
+  timeRange: string,
+  fetchOptions: FetchOptions,
+  display: DisplayOptions
+): Promise<LogEntry[]> => {
+  const author = authors.join(", ");
+  const quiet =
+    display.format === "json" ||
+    display.format === "markdown" ||
+    display.format === "count" ||
+    display.format === "template" ||
+    display.format === "tsv" ||
+    (display.format === "csv" && !display.csvPath);
+  const logs = sortLogs(
+    await fetchLogsForAuthor(authors, timeRange, {
+      ...fetchOptions,
+      quiet: fetchOptions.quiet || quiet,
+    }),
+    display.sort
+  );
+
+  const scope = fetchOptions.revisionRange
+    ? `in ${fetchOptions.revisionRange}`
+    : `in the past ${timeRange}`;
+
+  switch (display.format) {
+    case "json":
+      displayLogsJson(logs, display);
+      break;
+    case "csv":
+      displayLogsCsv(logs, display, display.csvPath);
+      break;
+    case "tsv":
+      displayLogsTsv(logs, display);
+      break;
+    case "markdown":
+      displayLogsMarkdown(logs, display);
+      break;
+    case "count":
+      writeOutput(String(logs.length), display);
+      break;
+    case "template":
+      displayLogsTemplate(logs, display.template ?? "", display);
+      break;
+    default:
+      if (logs.length === 0 && fetchOptions.grep) {
+        console.log(
+          `\nNo commits matched "${fetchOptions.grep}" for ${author} ${scope}.`
+        );
+        break;
+      }
+      displayLogsTable(author, scope, logs, display);
+  }
+
+  return logs;
+};
+
+// Fetch only the oldest matching commit
+const getFirstCommitOptions = (fetchOptions: FetchOptions): FetchOptions => ({
+  ...fetchOptions,
+  reverse: true,
+  limit: 1,
+});
+
+// Show the oldest commit by the given authors in the whole history, returning
+// it if there is one
+const showFirstCommit = async (
+  authors: string[],
+  fetchOptions: FetchOptions,
+  display: DisplayOptions
+): Promise<LogEntry | undefined> => {
+  const author = authors.join(", ");
+  const [first] = await fetchLogsForAuthor(authors, "", {
+    ...getFirstCommitOptions(fetchOptions),
+    quiet: display.format === "json" || display.format === "ndjson",
+  });
+
+  if (display.format === "json") {
+    displayLogsJson(first ? [first] : [], display);
+    return first;
+  }
+
+  if (display.format === "ndjson") {
+    if (first) {
+      writeOutput(JSON.stringify(first), display);
+    }
+    return first;
+  }
+
+  if (!first) {
+    console.log(`\nNo commits found for ${author}.`);
+    return first;
+  }
+
+  const table = createTable([], display);
+  table.push(
+    { Hash: first.commitHash },
+    { Date: `${first.date} (${first.relativeDate})` },
+    { Message: first.commitMessage },
+    { Author: first.authorName }
+  );
+
+  writeOutput(`\nFirst commit by ${author}:\n${table.toString()}`, display);
+
+  return first;
+};
+
+// Type for commit selection
+interface CommitSelection {
+  selectedHash: string;
+}
+
+// Clipboard commands to try, in order, for the current platform
+const CLIPBOARD_COMMANDS: Record<string, string[]> = {
+  darwin: ["pbcopy"],
+  win32: ["clip"],
+  linux: ["wl-copy", "xclip -selection clipboard", "xsel --clipboard --input"],
+};
+
+// Copy text to the system clipboard, returning whether it succeeded
+const copyToClipboard = (text: string): boolean => {
+  const commands = CLIPBOARD_COMMANDS[process.platform] ?? [];
+
+  return commands.some((command) => {
+    const result = spawnSync(command, {
+      input: text,
+      stdio: ["pipe", "ignore", "ignore"],
+      shell: true,
+    });
+    return !result.error && result.status === 0;
+  });
+};
+
+// Let the user pick one of the listed commits and copy its hash
+const pickCommit = async (logs: LogEntry[]): Promise<void> => {
+  const { selectedHash } = await inquirer.prompt<CommitSelection>([
+    {
+      type: "list",
+      name: "selectedHash",
+      message: "Select a commit to copy its hash:",
+      choices: logs.map((log) => ({
+        name: `${log.commitHash} ${log.commitMessage}`,
+        value: log.commitHash,
+      })),
+    },
+  ]);
+
+  if (copyToClipboard(selectedHash)) {
+    console.log(chalk.green(`Copied ${selectedHash} to the clipboard.`));
+  } else {
+    console.log(
+      chalk.yellow(`Could not access the clipboard. Commit hash: ${selectedHash}`)
+    );
+  }
+};
+
+// List value that leaves the --interactive commit browser
+const QUIT_CHOICE = "__quit__";
+
+// Render the author, date, full message and changed files of one commit as a
+// vertical table
+const renderCommitDetails = (
+  hash: string,
+  display: DisplayOptions,
+  ignoreMailmap: boolean = false
+): string => {
+  const identity = ignoreMailmap ? "%an%x1f%ae" : "%aN%x1f%aE";
+
+  try {
+    const [fullHash, name, email, timestamp, body] = execFileSync(
+      "git",
+      ["show", "-s", `--format=%H%x1f${identity}%x1f%aI%x1f%B`, hash],
+      { encoding: "utf8" }
+    ).split(FIELD_SEPARATOR);
+    const stat = execFileSync(
+      "git",
+      [
+        "show",
+        "--stat",
+        "--format=",
+        `--color=${display.color ? "always" : "never"}`,
+        hash,
+      ],
+      { encoding: "utf8" }
+    );
+
+    const table = createTable([], display);
+    table.push(
+      { Hash: chalk.yellow(fullHash) },
+      { Author: `${name} <${email}>` },
+      {
+        Date: formatDateInZone(
+          timestamp,
+          display.timeZone,
+          display.dateFormat ?? "YYYY-MM-DD HH:mm"
+        ),
+      },
+      { Message: body.trim() },
+      { Files: stat.trim() || "No files changed" }
+    );
+    return table.toString();
+  } catch (error) {
+    throw new WhoError("Could not show commit", { cause: error });
+  }
+};
+
+// List the commits whose hash starts with an ambiguous prefix, one per line
+const findCommitCandidates = (prefix: string): string[] => {
+  try {
+    const objects = execFileSync(
+      "git",
+      ["rev-parse", `--disambiguate=${prefix}`],
+      { encoding: "utf8" }
+    ).trim();
+    if (!objects) {
+      return [];
+    }
+
+    // The prefix matches trees and blobs too, which cannot be shown as commits
+    const commits = execFileSync(
+      "git",
+      ["cat-file", "--batch-check=%(objecttype) %(objectname)"],
+      { encoding: "utf8", input: `${objects}\n` }
+    )
+      .split("\n")
+      .filter((line) => line.startsWith("commit "))
+      .map((line) => line.slice("commit ".length));
+    if (commits.length === 0) {
+      return [];
+    }
+
+    return execFileSync(
+      "git",
+      ["show", "-s", "--format=%h  %as  %aN  %s", ...commits],
+      { encoding: "utf8" }
+    )
+      .trim()
+      .split("\n");
+  } catch {
+    return [];
+  }
+};
+
+// Resolve a full or abbreviated commit hash (or any revision) to a full hash.
+// A prefix shared by several commits fails with the list of candidates
+const resolveCommit = (revision: string): string => {
+  try {
+    return execFileSync(
+      "git",
+      ["rev-parse", "--verify", "--quiet", `${revision}^{commit}`],
+      { encoding: "utf8" }
+    ).trim();
+  } catch {
+    const candidates = /^[0-9a-f]{4,}$/i.test(revision)
+      ? findCommitCandidates(revision)
+      : [];
+
+    if (candidates.length > 1) {
+      throw new WhoError(
+        `"${revision}" is ambiguous, it matches ${candidates.length} commits.`,
+        {
+          hint: `Use a longer prefix, one of:\n${candidates
+            .map((candidate) => `  ${candidate}`)
+            .join("\n")}`,
+        }
+      );
+    }
+
+    throw new WhoError(`"${revision}" is not a commit.`);
+  }
+};
+
+// Show one commit, given by a full or abbreviated hash, in the table style
+const runShow = (args: string[], display: DisplayOptions): void => {
+  const [revision] = getPositionalArgs(args).slice(1);
+  if (revision === undefined) {
+    throw new WhoError("show needs a commit, e.g. git who show abc1234");
+  }
+
+  const hash = resolveCommit(revision);
+  writeOutput(
+    renderCommitDetails(hash, display, args.includes("--no-mailmap")),
+    display
+  );
+};
+
+// Let the user open the listed commits one at a time until they quit
+const browseCommits = async (
+  logs: LogEntry[],
+  display: DisplayOptions
+): Promise<void> => {
+  let selectedHash: string | undefined;
+
+  for (;;) {
+    ({ selectedHash } = await inquirer.prompt<CommitSelection>([
+      {
+        type: "list",
+        name: "selectedHash",
+        message: "Select a commit to inspect:",
+        default: selectedHash,
+        choices: [
+          ...logs.map((log) => ({
+            name: `${log.commitHash} ${log.commitMessage}`,
+            value: log.commitHash,
+          })),
+          { name: "Quit", value: QUIT_CHOICE },
+        ],
+      },
+    ]));
+
+    if (selectedHash === QUIT_CHOICE) {
+      return;
+    }
+
+    console.log(renderCommitDetails(selectedHash, display));
+  }
+};
+
+// Commands that open a URL in the default browser, tried in order for the
+// current platform; the URL is appended as the last argument
+const BROWSER_COMMANDS: Record<string, string[][]> = {
+  darwin: [["open"]],
+  win32: [["cmd", "/c", "start", ""]],
+  linux: [["xdg-open"], ["wslview"], ["gio", "open"]],
+};
+
+// Open a URL in the browser, returning whether one could be launched
+const openInBrowser = (url: string): boolean => {
+  const commands = process.env.BROWSER
+    ? [[process.env.BROWSER]]
+    : BROWSER_COMMANDS[process.platform] ?? [];
+
+  // Without a display (e.g. over SSH) xdg-open falls back to text browsers
+  const headless =
+    process.platform === "linux" &&
+    !process.env.BROWSER &&
+    !process.env.DISPLAY &&
+    !process.env.WAYLAND_DISPLAY;
+  if (headless) {
+    return false;
+  }
+
+  return commands.some(([command, ...rest]) => {
+    const result = spawnSync(command, [...rest, url], { stdio: "ignore" });
+    return !result.error && result.status === 0;
+  });
+};
+
+// Open a commit on GitHub or GitLab, picking one of the author's recent
+// commits when no hash is given
+const runOpen = async (
+  args: string[],
+  display: DisplayOptions,
+  config: WhoConfig
+): Promise<void> => {
+  const base = getCommitUrlBase();
+  if (!base) {
+    throw new WhoError(
+      "The origin remote is not on GitHub or GitLab, so there is no commit page to open."
+    );
+  }
+
+  let [revision] = getPositionalArgs(args).slice(1);
+
+  if (revision === undefined) {
+    requirePrompt(
+      args,
+      "git who open without a commit",
+      "Pass the commit to open, e.g. git who open abc1234."
+    );
+    const authors = await resolveAuthors(args, [], config);
+    const timeRange = await resolveTimeRange(args, config.timeRange);
+    const logs = await fetchLogsForAuthor(authors, timeRange, {
+      limit: parseLimit(config.limit),
+      timeZone: display.timeZone,
+      ignoreMailmap: args.includes("--no-mailmap"),
+    });
+
+    if (logs.length === 0) {
+      console.log(
+        `\nNo commits found for ${authors.join(", ")} in the past ${timeRange}.`
+      );
+      process.exitCode = EXIT_NO_COMMITS;
+      return;
+    }
+
+    ({ selectedHash: revision } = await inquirer.prompt<CommitSelection>([
+      {
+        type: "list",
+        name: "selectedHash",
+        message: "Select a commit to open:",
+        choices: logs.map((log) => ({
+          name: `${log.commitHash} ${log.commitMessage}`,
+          value: log.commitHash,
+        })),
+      },
+    ]));
+  }
+
+  const url = `${base}${resolveCommit(revision)}`;
+  if (openInBrowser(url)) {
+    console.log(`Opened ${url}`);
+  } else {
+    console.log(chalk.yellow(`Could not launch a browser. Commit URL: ${url}`));
+  }
+};
+
+// Format a count with a singular or plural noun, e.g. "1 commit", "2 commits"
+const pluralize = (count: number, noun: string): string =>
+  `${count} ${count === 1 ? noun : `${noun}s`}`;
+
+// Number of authors shown by `git who top` when --top is not given
+const DEFAULT_TOP = 10;
+
+// Count commits per author since a time range (or ever), most active first.
+// shortlog always applies .mailmap, so ignoreMailmap groups by the raw name.
+// Merges only record that work was combined, so they are left out of the
+// counts unless includeMerges is set
+const fetchLeaderboard = (
+  timeRange?: string,
+  ignoreMailmap: boolean = false,
+  includeMerges: boolean = false
+): [string, number][] => {
+  const since = timeRange ? [`--since=${timeRange}`] : [];
+  const group = ignoreMailmap ? ["--group=format:%an"] : [];
+  const merges = includeMerges ? [] : ["--no-merges"];
+
+  try {
+    const output = execFileSync(
+      "git",
+      ["shortlog", "-sn", ...group, ...merges, ...since, "HEAD"],
+      { timeout: gitTimeout }
+    )
+      .toString()
+      .trim();
+
+    if (!output) {
+      return [];
+    }
+
+    return output.split("\n").map((line) => {
+      const [, count, name] = line.match(/^\s*(\d+)\s+(.*)$/) ?? [];
+      return [name, Number(count)];
+    });
+  } catch (error) {
+    throw isTimeout(error)
+      ? timeoutError()
+      : new WhoError("Could not fetch contributors", { cause: error });
+  }
+};
+
+// Parse --top=N (or its alias --max), the number of rows shown by top and
+// churn
+const parseTop = (args: string[]): number => {
+  const flag =
+    hasFlag(args, "--max") && !hasFlag(args, "--top") ? "--max" : "--top";
+  const topValue = getFlagValue(args, flag);
+  const top = topValue === undefined ? DEFAULT_TOP : Number(topValue);
+
+  if (!Number.isInteger(top) || top < 1) {
+    throw new WhoError(`${flag} expects a positive number, got "${topValue}".`);
+  }
+
+  return top;
+};
+
+// Parse --min-commits=N, the fewest commits an author needs to be listed by
+// top; every author is listed without it
+const parseMinCommits = (args: string[]): number => {
+  const value = getFlagValue(args, "--min-commits");
+  const minCommits = value === undefined ? 1 : Number(value);
+
+  if (!Number.isInteger(minCommits) || minCommits < 1) {
+    throw new WhoError(
+      `--min-commits expects a positive number, got "${value}".`
+    );
+  }
+
+  return minCommits;
+};
+
+// Turn counts into percentages with one decimal that add up to exactly 100,
+// giving the tenths lost to rounding to the largest remainders (ties go to
+// the bigger share)
+const getPercentages = (counts: number[]): number[] => {
+  const total = counts.reduce((sum, count) => sum + count, 0);
+  const tenths = counts.map((count) => (count / total) * 1000);
+  const rounded = tenths.map(Math.floor);
+  let missing = 1000 - rounded.reduce((sum, value) => sum + value, 0);
+
+  tenths
+    .map((value, index) => ({
+      index,
+      value,
+      remainder: Math.round((value - Math.floor(value)) * 1e6),
+    }))
+    .sort((a, b) => b.remainder - a.remainder || b.value - a.value)
+    .forEach(({ index }) => {
+      if (missing > 0) {
+        rounded[index] += 1;
+        missing -= 1;
+      }
+    });
+
+  return rounded.map((value) => value / 10);
+};
+
+// Show a leaderboard of commit counts per author
+const runTop = async (
+  args: string[],
+  display: DisplayOptions,
+  config: WhoConfig
+): Promise<void> => {
+  const timeRange = await resolveTimeRange(args, config.timeRange);
+  const top = parseTop(args);
+  const minCommits = parseMinCommits(args);
+
+  const spinner = startSpinner("Counting commits per author...");
+  const everyone = fetchLeaderboard(
+    timeRange,
+    args.includes("--no-mailmap"),
+    args.includes("--include-merges")
+  );
+  spinner.succeed("Commits counted!");
+
+  if (everyone.length === 0) {
+    console.log(`\nNo commits found in the past ${timeRange}.`);
+    process.exitCode = EXIT_NO_COMMITS;
+    return;
+  }
+
+  // Shares stay relative to every commit, including the authors left out.
+  // The leaderboard is sorted, so the authors kept line up with their shares
+  const shares = getPercentages(everyone.map(([, count]) => count));
+  const regulars = everyone.filter(([, count]) => count >= minCommits);
+
+  if (regulars.length === 0) {
+    console.log(
+      `\nNo authors with at least ${pluralize(minCommits, "commit")} ` +
+        `in the past ${timeRange}.`
+    );
+    return;
+  }
+
+  const table = createTable(["#", "Author", "Commits", "% of total"], display);
+  regulars.slice(0, top).forEach(([name, count], index) => {
+    table.push([
+      String(index + 1),
+      name,
+      chalk.yellow(String(count)),
+      chalk.green(`${shares[index].toFixed(1)}%`),
+    ]);
+  });
+
+  writeOutput(
+    `\nTop contributors in the past ${timeRange}:\n${table.toString()}`,
+    display
+  );
+};
+
+// Share of commits the bus-factor authors must cover without --coverage
+const DEFAULT_COVERAGE = 80;
+
+// Parse --coverage=N, a percentage above 0 and up to 100
+const parseCoverage = (args: string[]): number => {
+  const value = getFlagValue(args, "--coverage");
+  const coverage = value === undefined ? DEFAULT_COVERAGE : Number(value);
+
+  if (!Number.isFinite(coverage) || coverage <= 0 || coverage > 100) {
+    throw new WhoError(
+      `--coverage expects a percentage between 0 and 100, got "${value}".`
+    );
+  }
+
+  return coverage;
+};
+
+// Show the smallest set of authors who together made --coverage percent of the
+// commits in the time range; its size is the bus factor
+const runBusFactor = async (
+  args: string[],
+  display: DisplayOptions,
+  config: WhoConfig
+): Promise<void> => {
+  const timeRange = await resolveTimeRange(args, config.timeRange);
+  const coverage = parseCoverage(args);
+
+  const spinner = startSpinner("Counting commits per author...");
+  const everyone = fetchLeaderboard(
+    timeRange,
+    args.includes("--no-mailmap"),
+    args.includes("--include-merges")
+  );
+  spinner.succeed("Commits counted!");
+
+  if (everyone.length === 0) {
+    console.log(`\nNo commits found in the past ${timeRange}.`);
+    process.exitCode = EXIT_NO_COMMITS;
+    return;
+  }
+
+  // The leaderboard is sorted, so take authors until the coverage is reached
+  const total = everyone.reduce((sum, [, count]) => sum + count, 0);
+  const shares = getPercentages(everyone.map(([, count]) => count));
+  const table = createTable(
+    ["#", "Author", "Commits", "% of total", "Cumulative"],
+    display
+  );
+  let covered = 0;
+  let busFactor = 0;
+
+  while (busFactor < everyone.length && (covered / total) * 100 < coverage) {
+    const [name, count] = everyone[busFactor];
+    covered += count;
+    table.push([
+      String(busFactor + 1),
+      name,
+      chalk.yellow(String(count)),
+      chalk.green(`${shares[busFactor].toFixed(1)}%`),
+      `${((covered / total) * 100).toFixed(1)}%`,
+    ]);
+    busFactor += 1;
+  }
+
+  writeOutput(
+    `\nAuthors covering ${coverage}% of ${pluralize(total, "commit")} ` +
+      `in the past ${timeRange}:\n${table.toString()}\n` +
+      chalk.bold(`Bus factor: ${busFactor}`),
+    display
+  );
+};
+
+// Show a key/value overview of the repository, optionally scoped with --T
+const runSummary = async (
+  args: string[],
+  display: DisplayOptions
+): Promise<void> => {
+  const timeRange = hasFlag(args, "--T")
+    ? await resolveTimeRange(args)
+    : undefined;
+  const since = timeRange ? [`--since=${timeRange}`] : [];
+  // Count the same commits as the busiest author's total
+  const includeMerges = args.includes("--include-merges");
+  const merges = includeMerges ? [] : ["--no-merges"];
+
+  const spinner = startSpinner("Summarizing repository...");
+  let dates: string[];
+
+  try {
+    dates = execFileSync(
+      "git",
+      ["log", "--format=%ad", "--date=short", ...merges, ...since],
+      { maxBuffer: MAX_BUFFER, timeout: gitTimeout }
+    )
+      .toString()
+      .split("\n")
+      .filter((date) => date !== "");
+  } catch (error) {
+    spinner.fail("Failed to summarize repository");
+    throw isTimeout(error)
+      ? timeoutError()
+      : new WhoError("Could not fetch logs", { cause: error });
+  }
+
+  const leaderboard = fetchLeaderboard(
+    timeRange,
+    args.includes("--no-mailmap"),
+    includeMerges
+  );
+  spinner.succeed("Repository summarized!");
+
+  if (dates.length === 0) {
+    console.log(`\nNo commits found in the past ${timeRange}.`);
+    process.exitCode = EXIT_NO_COMMITS;
+    return;
+  }
+
+  const sortedDates = [...dates].sort();
+  const [busiestName, busiestCount] = leaderboard[0];
+  const table = createTable([], display);
+  table.push(
+    { "Total commits": String(dates.length) },
+    { Contributors: String(leaderboard.length) },
+    { "First commit": sortedDates[0] },
+    { "Last commit": sortedDates[sortedDates.length - 1] },
+    {
+      "Busiest author": `${busiestName} (${pluralize(busiestCount, "commit")})`,
+    }
+  );
+
+  const scope = timeRange ? ` in the past ${timeRange}` : "";
+  writeOutput(`\nRepository summary${scope}:\n${table.toString()}`, display);
+};
+
+// Count how many lines of a file each author owns at HEAD, most first
+const fetchLineOwnership = (file: string): [string, number][] => {
+  const counts = new Map<string, number>();
+
+  try {
+    const output = execFileSync(
+      "git",
+      ["blame", "--line-porcelain", "HEAD", "--", file],
+      {
+        maxBuffer: MAX_BUFFER,
+        stdio: ["ignore", "pipe", "pipe"],
+        timeout: gitTimeout,
+      }
+    ).toString();
+
+    output.split("\n").forEach((line) => {
+      if (line.startsWith("author ")) {
+        const name = line.slice("author ".length);
+        counts.set(name, (counts.get(name) ?? 0) + 1);
+      }
+    });
+  } catch (error) {
+    if (isTimeout(error)) {
+      throw timeoutError();
+    }
+    const stderr = (error as { stderr?: Buffer }).stderr?.toString().trim();
+    throw new WhoError(`Could not blame ${file}: ${stderr || "git failed"}`);
+  }
+
+  return [...counts].sort((a, b) => b[1] - a[1]);
+};
+
+// Show which authors own the current lines of a file
+const runBlame = (file: string | undefined, display: DisplayOptions): void => {
+  if (!file) {
+    throw new WhoError("git who blame expects a file path.");
+  }
+
+  const status = execFileSync("git", ["status", "--porcelain", "--", file])
+    .toString()
+    .trim();
+
+  if (status) {
+    console.warn(
+      chalk.yellow(
+        `Warning: ${file} has uncommitted changes, results reflect HEAD.`
+      )
+    );
+  }
+
+  const spinner = startSpinner(`Blaming ${file}...`);
+  const ownership = fetchLineOwnership(file);
+  spinner.succeed("Lines counted!");
+
+  if (ownership.length === 0) {
+    console.log(`\n${file} has no lines at HEAD.`);
+    process.exitCode = EXIT_NO_COMMITS;
+    return;
+  }
+
+  const total = ownership.reduce((sum, [, lines]) => sum + lines, 0);
+  const table = createTable(["Author", "Lines", "Share"], display);
+  ownership.forEach(([name, lines]) => {
+    const share = `${((lines / total) * 100).toFixed(1)}%`;
+    table.push([name, chalk.yellow(String(lines)), share]);
+  });
+
+  const title = `Line ownership of ${file} (${pluralize(total, "line")}):`;
+  writeOutput(`\n${title}\n${table.toString()}`, display);
+};
+
+// Count how many commits touched each file since a time range, most first.
+// Files that no longer exist are skipped, and renames count under the new name
+const fetchFileChurn = (
+  timeRange: string,
+  author?: string,
+  authorRegex: boolean = false
+): [string, number][] => {
+  const logArgs = ["log", "--name-only", "--format=", `--since=${timeRange}`];
+  if (author) {
+    logArgs.push(...getAuthorArgs([author], authorRegex));
+  }
+
+  try {
+    const options = { maxBuffer: MAX_BUFFER, timeout: gitTimeout };
+    const output = execFileSync("git", logArgs, options);
+    // The log prints paths from the repository root, so list the tracked
+    // files from there too, whichever directory this runs in
+    const toplevel = execSync("git rev-parse --show-toplevel", options)
+      .toString()
+      .trim();
+    const tracked = new Set(
+      execFileSync(
+        "git",
+        ["-C", toplevel, "ls-files", "--full-name"],
+        options
+      )
+        .toString()
+        .split("\n")
+    );
+    const counts = new Map<string, number>();
+
+    output
+      .toString()
+      .split("\n")
+      .filter((path) => path !== "" && tracked.has(path))
+      .forEach((path) => counts.set(path, (counts.get(path) ?? 0) + 1));
+
+    return [...counts].sort((a, b) => b[1] - a[1]);
+  } catch (error) {
+    throw isTimeout(error)
+      ? timeoutError()
+      : new WhoError("Could not fetch file changes", { cause: error });
+  }
+};
+
+// Show the files changed most often in a time range
+const runChurn = async (
+  args: string[],
+  display: DisplayOptions,
+  config: WhoConfig
+): Promise<void> => {
+  const timeRange = await resolveTimeRange(args, config.timeRange);
+  const author = getFlagValue(args, "--author");
+  if (author !== undefined) {
+    validateAuthor(author);
+  }
+  const top = parseTop(args);
+  const by = author ? ` by ${author}` : "";
+
+  const spinner = startSpinner("Counting file changes...");
+  const churn = fetchFileChurn(
+    timeRange,
+    author,
+    args.includes("--author-regex")
+  ).slice(0, top);
+  spinner.succeed("File changes counted!");
+
+  if (churn.length === 0) {
+    console.log(`\nNo files changed${by} in the past ${timeRange}.`);
+    process.exitCode = EXIT_NO_COMMITS;
+    return;
+  }
+
+  const table = createTable(["#", "File", "Commits"], display);
+  churn.forEach(([path, count], index) => {
+    table.push([String(index + 1), path, chalk.yellow(String(count))]);
+  });
+
+  const title = `Most changed files${by} in the past ${timeRange}:`;
+  writeOutput(`\n${title}\n${table.toString()}`, display);
+};
+
+// Contributors whose last commit is older than this count as inactive
+const DEFAULT_INACTIVE_THRESHOLD = "6 months ago";
+
+// Most recent commit of one contributor
+interface LastCommit {
+  name: string;
+  epoch: number;
+  timestamp: string;
+  relativeDate: string;
+}
+
+// Find each contributor's most recent commit, oldest first
+const fetchLastCommits = (
+  all: boolean,
+  ignoreMailmap: boolean = false
+): LastCommit[] => {
+  try {
+    const output = execFileSync(
+      "git",
+      [
+        "log",
+        all ? "--all" : "HEAD",
+        `--format=${ignoreMailmap ? "%an" : "%aN"}%x1f%ct%x1f%cI%x1f%cr`,
+      ],
+      { encoding: "utf8", maxBuffer: MAX_BUFFER, timeout: gitTimeout }
+    ).trim();
+
+    // Rebased or merged history is not strictly ordered by date, so the
+    // newest commit per author is picked by timestamp
+    const latest = new Map<string, LastCommit>();
+    output
+      .split("\n")
+      .filter(Boolean)
+      .forEach((line) => {
+        const [name, epoch, timestamp, relativeDate] = line.split("\x1f");
+        const seen = latest.get(name);
+        if (!seen || Number(epoch) > seen.epoch) {
+          latest.set(name, {
+            name,
+            epoch: Number(epoch),
+            timestamp,
+            relativeDate,
+          });
+        }
+      });
+
+    return [...latest.values()].sort((a, b) => a.epoch - b.epoch);
+  } catch (error) {
+    throw isTimeout(error)
+      ? timeoutError()
+      : new WhoError("Could not fetch contributors", { cause: error });
+  }
+};
+
+// Turn a git date such as "6 months ago" into a Unix timestamp, using git's
+// own date parser so it agrees with --since
+const resolveGitDate = (date: string): number => {
+  const output = execFileSync("git", ["rev-parse", `--since=${date}`], {
+    encoding: "utf8",
+  }).trim();
+  return Number(output.replace("--max-age=", ""));
+};
+
+// List contributors who have not committed since --threshold, longest
+// inactive first
+const runInactive = async (
+  args: string[],
+  display: DisplayOptions
+): Promise<void> => {
+  const threshold =
+    getFlagValue(args, "--threshold") ?? DEFAULT_INACTIVE_THRESHOLD;
+  validateTimeRange(threshold, "--threshold");
+  const cutoff = resolveGitDate(threshold);
+
+  const spinner = startSpinner("Finding each contributor's last commit...");
+  const inactive = fetchLastCommits(
+    args.includes("--all"),
+    args.includes("--no-mailmap")
+  ).filter((contributor) => contributor.epoch < cutoff);
+  spinner.succeed("Contributors checked!");
+
+  if (inactive.length === 0) {
+    console.log(`\nEveryone has committed since ${threshold}.`);
+    process.exitCode = EXIT_NO_COMMITS;
+    return;
+  }
+
+  const table = createTable(["Author", "Last commit", "Age"], display);
+  inactive.forEach((contributor) => {
+    table.push([
+      contributor.name,
+      paint(display.colors.date)(
+        formatDateInZone(
+          contributor.timestamp,
+          display.timeZone,
+          display.dateFormat
+        )
+      ),
+      chalk.yellow(contributor.relativeDate),
+    ]);
+  });
+
+  writeOutput(
+    `\nContributors with no commits since ${threshold}:\n${table.toString()}`,
+    display
+  );
+};
+
+// A contributor's earliest commit, used to spot newcomers
+interface FirstCommit {
+  name: string;
+  epoch: number;
+  timestamp: string;
+  subject: string;
+}
+
+// Find each contributor's first commit by author date, newest first
+const fetchFirstCommits = (
+  all: boolean,
+  ignoreMailmap: boolean = false
+): FirstCommit[] => {
+  try {
+    const output = execFileSync(
+      "git",
+      [
+        "log",
+        all ? "--all" : "HEAD",
+        `--format=${ignoreMailmap ? "%an" : "%aN"}%x1f%at%x1f%aI%x1f%s`,
+      ],
+      { encoding: "utf8", maxBuffer: MAX_BUFFER, timeout: gitTimeout }
+    ).trim();
+
+    // As with the last commits, history is not strictly ordered by date
+    const earliest = new Map<string, FirstCommit>();
+    output
+      .split("\n")
+      .filter(Boolean)
+      .forEach((line) => {
+        const [name, epoch, timestamp, subject] = line.split("\x1f");
+        const seen = earliest.get(name);
+        if (!seen || Number(epoch) <= seen.epoch) {
+          earliest.set(name, {
+            name,
+            epoch: Number(epoch),
+            timestamp,
+            subject,
+          });
+        }
+      });
+
+    return [...earliest.values()].sort((a, b) => b.epoch - a.epoch);
+  } catch (error) {
+    throw isTimeout(error)
+      ? timeoutError()
+      : new WhoError("Could not fetch contributors", { cause: error });
+  }
+};
+
+// List the contributors whose first ever commit falls in the time range
+const runOnboarding = async (
+  args: string[],
+  display: DisplayOptions,
+  config: WhoConfig
+): Promise<void> => {
+  const timeRange = await resolveTimeRange(args, config.timeRange);
+  const cutoff = resolveGitDate(timeRange);
+
+  const spinner = startSpinner("Finding each contributor's first commit...");
+  const newcomers = fetchFirstCommits(
+    args.includes("--all"),
+    args.includes("--no-mailmap")
+  ).filter((contributor) => contributor.epoch >= cutoff);
+  spinner.succeed("Contributors checked!");
+
+  if (newcomers.length === 0) {
+    console.log(`\nNo first-time contributors in the past ${timeRange}.`);
+    process.exitCode = EXIT_NO_COMMITS;
+    return;
+  }
+
+  const table = createTable(["Author", "First commit", "Message"], display);
+  newcomers.forEach((contributor) => {
+    table.push([
+      contributor.name,
+      paint(display.colors.date)(
+        formatDateInZone(
+          contributor.timestamp,
+          display.timeZone,
+          display.dateFormat
+        )
+      ),
+      contributor.subject,
+    ]);
+  });
+
+  writeOutput(
+    `\nFirst-time contributors in the past ${timeRange}:\n${table.toString()}`,
+    display
+  );
+};
+
+// Show the full patches of an author's commits, paged and colored by git
+// itself so core.pager and diff highlighters keep working
+const runDiff = async (
+  args: string[],
+  display: DisplayOptions,
+  config: WhoConfig
+): Promise<void> => {
+  const authors = await resolveAuthors(
+    args,
+    getPositionalArgs(args).slice(1),
+    config
+  );
+  const timeRange = await resolveTimeRange(args, config.timeRange);
+  const paths = getFlagValues(args, "--path");
+
+  const filters = [
+    ...getAuthorArgs(authors, args.includes("--author-regex")),
+    `--since=${timeRange}`,
+    ...(paths.length ? ["--", ...paths] : []),
+  ];
+  const command = [
+    ...(display.pager && !display.outputPath ? [] : ["--no-pager"]),
+    "log",
+    "-p",
+    ...(display.color ? [] : ["--no-color"]),
+    ...filters,
+  ];
+
+  // git prints nothing when no commit matches, so check first to exit with 2
+  // like the other queries
+  const first = spawnSync("git", ["log", "-1", "--format=%h", ...filters], {
+    encoding: "utf-8",
+    timeout: gitTimeout,
+  });
+  if (first.error && isTimeout(first.error)) {
+    throw timeoutError();
+  }
+  if (first.status === 0 && first.stdout.trim() === "") {
+    console.log(
+      `\nNo commits found for ${authors.join(", ")} in the past ${timeRange}.`
+    );
+    process.exitCode = EXIT_NO_COMMITS;
+    return;
+  }
+
+  if (display.outputPath) {
+    const result = spawnSync("git", command, {
+      maxBuffer: MAX_BUFFER,
+      timeout: gitTimeout,
+    });
+    if (result.error && isTimeout(result.error)) {
+      throw timeoutError();
+    }
+    if (result.status !== 0) {
+      throw new WhoError(
+        `Could not fetch diffs: ${result.stderr.toString().trim()}`
+      );
+    }
+    writeOutput(result.stdout.toString().trimEnd(), display);
+    return;
+  }
+
+  // git has already printed why it failed, so only the exit code is passed on.
+  // No timeout here: the pager stays open for as long as the user reads
+  const result = spawnSync("git", command, { stdio: "inherit" });
+  if (result.status !== 0) {
+    throw new WhoError("", { exitCode: result.status ?? 1 });
+  }
+};
+
+// Show the current user's commits across all branches since yesterday
+const runStandup = async (
+  args: string[],
+  display: DisplayOptions
+): Promise<void> => {
+  const since = getFlagValue(args, "--since") ?? "yesterday";
+  validateTimeRange(since, "--since");
+
+  const logs = await showLogs(
+    [getCurrentUser()],
+    since,
+    { all: true, limit: 0, timeZone: display.timeZone },
+    display
+  );
+
+  if (logs.length === 0) {
+    process.exitCode = EXIT_NO_COMMITS;
+  }
+};
+
+// Totals compared side by side by `git who compare`
+const getCompareMetrics = (logs: LogEntry[]): [string, number][] => [
+  ["Commits", logs.length],
+  ["Lines added", logs.reduce((sum, log) => sum + (log.insertions ?? 0), 0)],
+  ["Lines deleted", logs.reduce((sum, log) => sum + (log.deletions ?? 0), 0)],
+  ["Files touched", new Set(logs.flatMap((log) => log.files ?? [])).size],
+];
+
+// Compare two authors' activity in the time range: their commits interleaved
+// by date, then a summary with the larger value of each metric highlighted
+const runCompare = async (
+  args: string[],
+  display: DisplayOptions,
+  config: WhoConfig
+): Promise<void> => {
+  const authors = await resolveAuthors(
+    args,
+    getPositionalArgs(args).slice(1),
+    config
+  );
+
+  if (authors.length !== 2) {
+    throw new WhoError(
+      'compare needs exactly two authors, e.g. git who compare "Jane Doe" "John Doe" (or pick two with --t).'
+    );
+  }
+
+  const timeRange = await resolveTimeRange(args, config.timeRange);
+  const options: FetchOptions = {
+    quiet: true,
+    stat: true,
+    limit: 0,
+    timeZone: display.timeZone,
+    ignoreMailmap: args.includes("--no-mailmap"),
+  };
+
+  const spinner = startSpinner(
+    `Fetching commits for ${authors.join(" and ")}...`
+  );
+  const [first, second] = await Promise.all(
+    authors.map((author) => fetchLogsForAuthor([author], timeRange, options))
+  );
+  spinner.succeed("Logs fetched successfully!");
+
+  const title = `${authors[0]} vs ${authors[1]} in the past ${timeRange}`;
+  const logs = [...first, ...second].sort(
+    (a, b) => Date.parse(b.timestamp) - Date.parse(a.timestamp)
+  );
+  const sections = [`\n${title}:`];
+
+  if (logs.length) {
+    sections.push(renderLogsTable(logs, { ...display, showStat: true }));
+  }
+
+  const summary = createTable(["", ...authors], display);
+  const secondMetrics = getCompareMetrics(second);
+  getCompareMetrics(first).forEach(([metric, a], index) => {
+    const b = secondMetrics[index][1];
+    const highlight = (value: number, other: number): string =>
+      value > other ? chalk.bold.green(String(value)) : String(value);
+    summary.push([metric, highlight(a, b), highlight(b, a)]);
+  });
+  sections.push(summary.toString());
+
+  writeOutput(sections.join("\n"), display);
+
+  if (logs.length === 0) {
+    process.exitCode = EXIT_NO_COMMITS;
+  }
+};
+
+// Group log entries by author, most commits first, then by day
+const groupDigest = (
+  logs: LogEntry[]
+): [string, [string, LogEntry[]][]][] => {
+  const byAuthor = new Map<string, LogEntry[]>();
+  logs.forEach((log) => {
+    byAuthor.set(log.authorName, [
+      ...(byAuthor.get(log.authorName) ?? []),
+      log,
+    ]);
+  });
+
+  return [...byAuthor]
+    .sort(([, a], [, b]) => b.length - a.length)
+    .map(([author, commits]) => [author, groupLogs(commits, "day")]);
+};
+
+// Escape text for use in HTML
+const escapeHtml = (text: string): string =>
+  text
+    .replace(/&/g, "&amp;")
+    .replace(/</g, "&lt;")
+    .replace(/>/g, "&gt;")
+    .replace(/"/g, "&quot;");
+
+// Render the digest as plain text, ready to paste into an email
+const renderDigestText = (
+  title: string,
+  groups: [string, [string, LogEntry[]][]][]
+): string => {
+  const lines = [title, "=".repeat(title.length)];
+
+  groups.forEach(([author, days]) => {
+    const count = days.reduce((sum, [, commits]) => sum + commits.length, 0);
+    lines.push("", `${author} (${pluralize(count, "commit")})`);
+    days.forEach(([day, commits]) => {
+      lines.push(`  ${day}`);
+      commits.forEach((log) => {
+        lines.push(`    - ${log.commitHash} ${log.commitMessage}`);
+      });
+    });
+  });
+
+  return lines.join("\n");
+};
+
+// Inline styles for the HTML digest, since mail clients drop <style> blocks
+// and external stylesheets
+const DIGEST_STYLES = {
+  body: "font-family:-apple-system,Segoe UI,Helvetica,Arial,sans-serif;color:#1f2328;max-width:720px;margin:0 auto;padding:16px;",
+  title: "font-size:22px;",
+  author: "margin:24px 0 4px;font-size:18px;border-bottom:1px solid #d0d7de;",
+  count: "font-weight:normal;color:#57606a;",
+  day: "margin:12px 0 4px;font-size:14px;color:#57606a;",
+  list: "margin:0;padding-left:20px;",
+  item: "margin:2px 0;",
+  hash: "color:#0969da;",
+};
+
+// Render the digest as a self-contained HTML page
+const renderDigestHtml = (
+  title: string,
+  groups: [string, [string, LogEntry[]][]][]
+): string => {
+  const lines = [
+    "<!DOCTYPE html>",
+    `<html><head><meta charset="utf-8"><title>${escapeHtml(title)}</title></head>`,
+    `<body style="${DIGEST_STYLES.body}">`,
+    `<h1 style="${DIGEST_STYLES.title}">${escapeHtml(title)}</h1>`,
+  ];
+
+  groups.forEach(([author, days]) => {
+    const count = days.reduce((sum, [, commits]) => sum + commits.length, 0);
+    lines.push(
+      `<h2 style="${DIGEST_STYLES.author}">${escapeHtml(author)} ` +
+        `<span style="${DIGEST_STYLES.count}">(${pluralize(count, "commit")})</span></h2>`
+    );
+    days.forEach(([day, commits]) => {
+      lines.push(
+        `<h3 style="${DIGEST_STYLES.day}">${escapeHtml(day)}</h3>`,
+        `<ul style="${DIGEST_STYLES.list}">`,
+        ...commits.map(
+          (log) =>
+            `<li style="${DIGEST_STYLES.item}">` +
+            `<code style="${DIGEST_STYLES.hash}">${escapeHtml(log.commitHash)}</code> ` +
+            `${escapeHtml(log.commitMessage)}</li>`
+        ),
+        "</ul>"
+      );
+    });
+  });
+
+  lines.push("</body></html>");
+  return lines.join("\n");
+};
+
+// Print a digest of commits in the time range, grouped by author and day, as
+// plain text or (with --html) HTML for a weekly email. --team covers everyone
+const runEmailReport = async (
+  args: string[],
+  display: DisplayOptions,
+  config: WhoConfig
+): Promise<void> => {
+  const team = args.includes("--team");
+  const authors = team
+    ? []
+    : await resolveAuthors(args, getPositionalArgs(args).slice(1), config);
+  const timeRange = await resolveTimeRange(args, config.timeRange);
+
+  const spinner = startSpinner("Collecting commits for the digest...");
+  const logs = await fetchLogsForAuthor(authors, timeRange, {
+    quiet: true,
+    limit: 0,
+    merges: "exclude",
+    timeZone: display.timeZone,
+    ignoreMailmap: args.includes("--no-mailmap"),
+  });
+  spinner.succeed("Commits collected!");
+
+  const who = team ? "the team" : authors.join(", ");
+  const title = `Commits by ${who} in the past ${timeRange}`;
+  const groups = groupDigest(logs);
+
+  if (groups.length === 0) {
+    console.log(`\nNo commits found for ${who} in the past ${timeRange}.`);
+    process.exitCode = EXIT_NO_COMMITS;
+    return;
+  }
+
+  writeOutput(
+    args.includes("--html")
+      ? renderDigestHtml(title, groups)
+      : renderDigestText(title, groups),
+    display
+  );
+};
+
+// A tag with the person who created it and the commit it points at
+interface TagInfo {
+  name: string;
+  timestamp: string;
+  tagger: string;
+  author: string;
+  hash: string;
+  subject: string;
+}
+
+// List all tags, newest first. Annotated tags have a tagger and point at the
+// commit through * fields; lightweight tags point at the commit directly
+const fetchTags = (): TagInfo[] => {
+  const fields = [
+    "%(refname:short)",
+    "%(creatordate:iso-strict)",
+    "%(taggername) %(taggeremail)",
+    "%(*authorname) %(*authoremail)",
+    "%(authorname) %(authoremail)",
+    "%(*objectname:short)",
+    "%(objectname:short)",
+    "%(*subject)",
+    "%(subject)",
+  ];
+
+  try {
+    const output = execFileSync(
+      "git",
+      [
+        "for-each-ref",
+        "--sort=-creatordate",
+        `--format=${fields.join("%1f")}`,
+        "refs/tags",
+      ],
+      { encoding: "utf8", timeout: gitTimeout }
+    ).trim();
+
+    return output
+      .split("\n")
+      .filter(Boolean)
+      .map((line) => {
+        const [name, timestamp, tagger, ...rest] = line.split(FIELD_SEPARATOR);
+        const [peeledAuthor, author, peeledHash, hash, peeledSubject, subject] =
+          rest.map((field) => field.trim());
+        const annotated = tagger.trim() !== "";
+        return {
+          name,
+          timestamp,
+          tagger: tagger.trim(),
+          author: annotated ? peeledAuthor : author,
+          hash: annotated ? peeledHash : hash,
+          subject: annotated ? peeledSubject : subject,
+        };
+      });
+  } catch (error) {
+    throw isTimeout(error)
+      ? timeoutError()
+      : new WhoError("Could not fetch tags", { cause: error });
+  }
+};
+
+// Search every author's commit messages on all branches for a text, most
+// recent first. --author narrows it to one person and -i ignores case
+const runSearch = async (
+  args: string[],
+  display: DisplayOptions,
+  config: WhoConfig
+): Promise<void> => {
+  const query = getPositionalArgs(args).slice(1).join(" ");
+  if (query.trim() === "") {
+    throw new WhoError(
+      'search needs a text to look for, e.g. git who search "login bug"'
+    );
+  }
+
+  const author = getFlagValue(args, "--author");
+  if (author !== undefined) {
+    validateAuthor(author);
+  }
+
+  // The text is searched for as typed unless --regex (or --author-regex,
+  // which git applies to every pattern) asks for a regular expression
+  const authorRegex = args.includes("--author-regex");
+  const regex = authorRegex || args.includes("--regex");
+
+  const spinner = startSpinner(`Searching commit messages for "${query}"...`);
+  const logs = await fetchLogsForAuthor(author ? [author] : [], "", {
+    quiet: true,
+    all: true,
+    grep: query,
+    ignoreCase: args.includes("-i"),
+    limit: parseLimit(
+      getFlagValue(args, "--limit") ?? getFlagValue(args, "-n") ?? config.limit
+    ),
+    authorRegex,
+    fixedStrings: !regex,
+    timeZone: display.timeZone,
+    ignoreMailmap: args.includes("--no-mailmap"),
+  });
+  spinner.succeed("Search finished!");
+  const by = author ? ` by ${author}` : "";
+
+  if (logs.length === 0) {
+    console.log(`\nNo commits${by} match "${query}".`);
+    process.exitCode = EXIT_NO_COMMITS;
+    return;
+  }
+
+  // Branches are walked by commit date, so rank by the date shown instead
+  const ranked = [...logs].sort(
+    (a, b) => Date.parse(b.timestamp) - Date.parse(a.timestamp)
+  );
+  printPaged(
+    `\nCommits${by} matching "${query}":\n${renderLogsTable(ranked, display)}`,
+    display
+  );
+};
+
+// List the tags the selected authors created, or that point at a commit they
+// authored, to see who cut which releases
+const runTags = async (
+  args: string[],
+  display: DisplayOptions,
+  config: WhoConfig
+): Promise<void> => {
+  const authors = await resolveAuthors(
+    args,
+    getPositionalArgs(args).slice(1),
+    config
+  );
+  const matches = (identity: string) =>
+    authors.some((author) => identity.includes(author));
+
+  const tags = fetchTags().filter(
+    (tag) => matches(tag.tagger) || matches(tag.author)
+  );
+  const who = authors.join(", ");
+
+  if (tags.length === 0) {
+    console.log(`\nNo tags found for ${who}.`);
+    process.exitCode = EXIT_NO_COMMITS;
+    return;
+  }
+
+  const table = createTable(
+    ["Tag", "Date", "Commit", "Subject", "Role"],
+    display
+  );
+  tags.forEach((tag) => {
+    const roles = [
+      ...(matches(tag.tagger) ? ["tagged"] : []),
+      ...(matches(tag.author) ? ["authored"] : []),
+    ];
+    table.push([
+      chalk.yellow(tag.name),
+      paint(display.colors.date)(
+        formatDateInZone(tag.timestamp, display.timeZone, display.dateFormat)
+      ),
+      tag.hash,
+      tag.subject,
+      roles.join(", "),
+    ]);
+  });
+
+  writeOutput(`\nTags by ${who}:\n${table.toString()}`, display);
+};
+
+// Reject author names git could never match, such as empty or multi-line ones
+const validateAuthor = (author: string): void => {
+  if (author.trim() === "") {
+    throw new WhoError("Author names cannot be empty.");
+  }
+
+  if (/[\x00-\x1f\x7f]/.test(author)) {
+    throw new WhoError(
+      `Author name ${JSON.stringify(author)} contains control characters.`
+    );
+  }
+};
+
+// Pick the authors from --t, the given names ("me" and --me included) or the
+// configured/current user
+const resolveAuthors = async (
+  args: string[],
+  names: string[],
+  config: WhoConfig
+): Promise<string[]> => {
+  if (args.includes("--t")) {
+    requirePrompt(
+      args,
+      "--t",
+      'Name the authors instead, e.g. git who "Jane Doe", or set contributor in the config file.'
+    );
+    return selectAuthors(
+      args.includes("--refresh"),
+      args.includes("--all"),
+      args.includes("--no-mailmap")
+    );
+  }
+
+  // "me" and --me stand for the current Git user
+  const authors = names.map((name) =>
+    name === "me" ? getCurrentUser() : name
+  );
+  if (args.includes("--me")) {
+    authors.push(getCurrentUser());
+  }
+  authors.forEach(validateAuthor);
+
+  return authors.length
+    ? [...new Set(authors)]
+    : [config.contributor || getCurrentUser()];
+};
+
+// Shades for 1-4 intensity levels, from fewest to most commits
+const HEATMAP_COLORS = ["#9be9a8", "#40c463", "#30a14e", "#216e39"];
+const HEATMAP_CHARS = ["░", "▒", "▓", "█"];
+const HEATMAP_WEEKS = 53;
+const WEEKDAY_LABELS = ["", "Mon", "", "Wed", "", "Fri", ""];
+const MONTH_LABELS = [
+  "Jan",
+  "Feb",
+  "Mar",
+  "Apr",
+  "May",
+  "Jun",
+  "Jul",
+  "Aug",
+  "Sep",
+  "Oct",
+  "Nov",
+  "Dec",
+];
+
+// Format a date as YYYY-MM-DD in local time, matching --date=short
+const formatShortDate = (date: Date): string =>
+  [
+    date.getFullYear(),
+    String(date.getMonth() + 1).padStart(2, "0"),
+    String(date.getDate()).padStart(2, "0"),
+  ].join("-");
+
+// Count commits per day for the given authors over the last year
+const fetchCommitCounts = (
+  authors: string[],
+  authorRegex: boolean = false
+): Map<string, number> => {
+  const command = [
+    "log",
+    ...getAuthorArgs(authors, authorRegex),
+    "--since=1 year ago",
+    "--format=%ad",
+    "--date=short",
+  ];
+
+  try {
+    const counts = new Map<string, number>();
+
+    execFileSync("git", command, { maxBuffer: MAX_BUFFER, timeout: gitTimeout })
+      .toString()
+      .split("\n")
+      .filter((date) => date !== "")
+      .forEach((date) => counts.set(date, (counts.get(date) ?? 0) + 1));
+
+    return counts;
+  } catch (error) {
+    throw isTimeout(error)
+      ? timeoutError()
+      : new WhoError("Could not fetch logs", { cause: error });
+  }
+};
+
+// Render a weekday by week grid of commit counts, like the GitHub profile graph
+const renderHeatmap = (
+  counts: Map<string, number>,
+  display: DisplayOptions
+): string => {
+  const today = new Date();
+  const start = new Date(today);
+  start.setDate(today.getDate() - today.getDay() - (HEATMAP_WEEKS - 1) * 7);
+
+  const max = Math.max(0, ...counts.values());
+  const rows: string[] = WEEKDAY_LABELS.map((label) => label.padEnd(4));
+  let monthRow = "    ";
+  let lastMonth = -1;
+
+  for (let week = 0; week < HEATMAP_WEEKS; week++) {
+    const weekStart = new Date(start);
+    weekStart.setDate(start.getDate() + week * 7);
+
+    // Label a column when it holds the first week of a new month
+    const month = weekStart.getMonth();
+    if (month !== lastMonth && monthRow.length <= 4 + week * 2) {
+      monthRow = monthRow.padEnd(4 + week * 2) + MONTH_LABELS[month];
+      lastMonth = month;
+    }
+
+    for (let weekday = 0; weekday < 7; weekday++) {
+      const day = new Date(weekStart);
+      day.setDate(weekStart.getDate() + weekday);
+
+      if (day > today) {
+        rows[weekday] += "  ";
+        continue;
+      }
+
+      const count = counts.get(formatShortDate(day)) ?? 0;
+      if (count === 0) {
+        rows[weekday] += "  ";
+        continue;
+      }
+
+      const level = Math.min(3, Math.ceil((count / max) * 4) - 1);
+      rows[weekday] += display.color
+        ? chalk.bgHex(HEATMAP_COLORS[level])("  ")
+        : HEATMAP_CHARS[level].repeat(2);
+    }
+  }
+
+  return [monthRow, ...rows].join("\n");
+};
+
+// Show a GitHub-style contribution heatmap for the last year
+const runHeatmap = async (
+  args: string[],
+  display: DisplayOptions,
+  config: WhoConfig
+): Promise<void> => {
+  const authors = await resolveAuthors(
+    args,
+    getPositionalArgs(args).slice(1),
+    config
+  );
+
+  const spinner = startSpinner(
+    `Counting commits for ${authors.join(", ")}...`
+  );
+  const counts = fetchCommitCounts(authors, args.includes("--author-regex"));
+  spinner.succeed("Commits counted!");
+
+  const total = [...counts.values()].reduce((sum, count) => sum + count, 0);
+
+  const commits = pluralize(total, "commit");
+  const names = authors.join(", ");
+
+  const title = `${commits} by ${names} in the last year:`;
+  writeOutput(`\n${title}\n\n${renderHeatmap(counts, display)}`, display);
+
+  if (total === 0) {
+    process.exitCode = EXIT_NO_COMMITS;
+  }
+};
+
+// Subcommands offered by shell completion
+const COMMANDS = [
+  "top",
+  "bus-factor",
+  "standup",
+  "heatmap",
+  "summary",
+  "blame",
+  "churn",
+  "inactive",
+  "onboarding",
+  "compare",
+  "show",
+  "open",
+  "email-report",
+  "search",
+  "tags",
+  "diff",
+  "config",
+  "completion",
+];
+
+// Long flags documented in the help text
+const getCompletionFlags = (): string[] =>
+  [...new Set(HELP_TEXT.match(/(?<![\w-])--[a-zA-Z][\w-]*/g) ?? [])].sort();
+
+// Shell command that lists the repository's authors for dynamic completion
+const AUTHORS_COMMAND = "git log --format=%an 2>/dev/null | sort -u";
+
+// Build the completion script for a shell, or undefined if it is unsupported
+const getCompletionScript = (shell: string): string | undefined => {
+  const flags = getCompletionFlags();
+
+  switch (shell) {
+    case "bash":
+      return `# git who completion for bash; needs git's bash completion loaded
+_git_who ()
+{
+	case "$cur" in
+	-*)
+		__gitcomp "${flags.join(" ")}"
+		return
+		;;
+	esac
+	local IFS=$'\\n'
+	COMPREPLY=($(compgen -W "${COMMANDS.join("\n")}
+$(${AUTHORS_COMMAND})" -- "$cur"))
+}`;
+    case "zsh":
+      return `# git who completion for zsh; hooks into zsh's git completion
+zstyle ':completion:*:*:git:*' user-commands who:'view logs by author and time range'
+_git-who () {
+  local -a commands flags authors
+  commands=(${COMMANDS.join(" ")})
+  flags=(${flags.join(" ")})
+  authors=(\${(f)"$(${AUTHORS_COMMAND})"})
+  if [[ $PREFIX == -* ]]; then
+    compadd -a flags
+  else
+    compadd -a commands authors
+  fi
+}`;
+    case "fish": {
+      const who = "complete -c git -n '__fish_seen_subcommand_from who'";
+      return [
+        "# git who completion for fish",
+        "complete -c git -n __fish_use_subcommand -f -a who " +
+          "-d 'View logs by author and time range'",
+        `${who} -f -a '${COMMANDS.join(" ")}'`,
+        `${who} -f -a '(${AUTHORS_COMMAND})'`,
+        ...flags.map((flag) => `${who} -l ${flag.slice(2)}`),
+      ].join("\n");
+    }
+    case "powershell": {
+      const words = [...COMMANDS, ...flags].map((word) => `'${word}'`);
+      return `# git who completion for PowerShell
+Register-ArgumentCompleter -Native -CommandName git -ScriptBlock {
+  param($wordToComplete, $commandAst, $cursorPosition)
+  $words = @($commandAst.CommandElements | ForEach-Object { $_.ToString() })
+  if ($words.Count -lt 2 -or $words[1] -ne 'who') { return }
+  $candidates = @(${words.join(", ")})
+  $candidates += git log --format=%an 2>$null | Sort-Object -Unique
+  $candidates | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+    $text = if ($_ -match '\\s') { "'$_'" } else { $_ }
+    [System.Management.Automation.CompletionResult]::new($text, $_, 'ParameterValue', $_)
+  }
+}`;
+    }
+    default:
+      return undefined;
+  }
+};
+
+// Print the completion script for the shell named on the command line
+const runCompletion = (shell: string | undefined): void => {
+  const script = shell && getCompletionScript(shell);
+
+  if (!script) {
+    throw new WhoError(
+      "git who completion expects one of bash, zsh, fish or powershell."
+    );
+  }
+
+  console.log(script);
+};
+
+// Seconds between refreshes of --watch when --interval is not given
+const DEFAULT_WATCH_INTERVAL = 5;
+
+// Redraw the logs every few seconds until interrupted with Ctrl-C
+const watchLogs = async (
+  args: string[],
+  authors: string[],
+  timeRange: string,
+  fetchOptions: FetchOptions,
+  display: DisplayOptions
+): Promise<void> => {
+  const intervalValue = getFlagValue(args, "--interval");
+  const interval =
+    intervalValue === undefined
+      ? DEFAULT_WATCH_INTERVAL
+      : Number(intervalValue);
+
+  if (!Number.isFinite(interval) || interval <= 0) {
+    throw new WhoError(
+      `--interval expects a positive number of seconds, got "${intervalValue}".`
+    );
+  }
+
+  if (!process.stdout.isTTY || display.outputPath) {
+    throw new WhoError("--watch needs a terminal to draw on.");
+  }
+
+  process.on("SIGINT", () => {
+    process.stdout.write("\n");
+    process.exit(0);
+  });
+
+  // A pager or spinner would get in the way of redrawing the screen
+  const watchDisplay = { ...display, pager: false };
+  const watchOptions = { ...fetchOptions, quiet: true };
+
+  while (true) {
+    console.clear();
+    await showLogs(authors, timeRange, watchOptions, watchDisplay);
+    const updated = new Date().toLocaleTimeString();
+    console.log(
+      chalk.gray(
+        `\nUpdated ${updated}, refreshing every ${interval}s. Press Ctrl-C to exit.`
+      )
+    );
+    await new Promise((resolve) => setTimeout(resolve, interval * 1000));
+  }
+};
+
+// Quote an argument for a POSIX shell, leaving plain words as they are
+const quoteShellArg = (arg: string): string =>
+  /^[\w@%+=:,./-]+$/.test(arg) ? arg : `'${arg.replace(/'/g, "'\\''")}'`;
+
+// Exit code for a query that worked but found no commits; errors exit with 1
+const EXIT_NO_COMMITS = 2;
+
+// Main function
+const main = async (): Promise<void> => {
+  const args = process.argv.slice(2);
+
+  if (args.includes("--help")) {
+    displayHelp();
+    return;
+  }
+
+  // Completion scripts and config changes work without a repository
+  const [command, ...commandArgs] = getPositionalArgs(args);
+
+  if (command === "completion") {
+    runCompletion(commandArgs[0]);
+    return;
+  }
+
+  // The config file belongs to the user, not to a repository
+  if (command === "config") {
+    runConfig(args.slice(args.indexOf("config") + 1));
+    return;
+  }
+
+  useRepository(args);
+  checkGitRepository();
+  gitTimeout = parseTimeout(getFlagValue(args, "--timeout"));
+
+  // Not a failed query: there is nothing to query yet
+  if (!hasCommits()) {
+    console.log("This repository has no commits yet.");
+    return;
+  }
+
+  const config = loadConfig();
+  const outputPath = fromInvocationDir(
+    getFlagValue(args, "--output") ?? getFlagValue(args, "-o")
+  );
+  const columns = parseColumns(args);
+  const theme = parseTheme(getFlagValue(args, "--theme") ?? config.theme);
+  const display: DisplayOptions = {
+    format: getOutputFormat(args),
+    template: parseFormatTemplate(args),
+    csvPath: fromInvocationDir(getFlagValue(args, "--csv")),
+    outputPath,
+    columns,
+    hideOrigin: args.includes("--no-origin"),
+    // Picking a column with --columns also fetches the data it needs
+    showEmail: args.includes("--email") || columns?.includes("email"),
+    showStat:
+      args.includes("--stat") ||
+      columns?.some((name) =>
+        ["files", "insertions", "deletions"].includes(name)
+      ),
+    showPaths:
+      args.includes("--files") ||
+      args.includes("--paths-changed") ||
+      columns?.includes("paths"),
+    showSignature:
+      args.includes("--show-signature") || columns?.includes("signed"),
+    flagFixups:
+      args.includes("--flag-fixups") || args.includes("--fixups-only"),
+    showDco:
+      args.includes("--dco") ||
+      args.includes("--dco-missing") ||
+      columns?.includes("dco"),
+    showAttribution: args.includes("--co-authors"),
+    showSummary: args.includes("--summary"),
+    workHours: parseWorkHours(args),
+    relativeDates: args.includes("--relative"),
+    maxMessageWidth: parseMaxMessageWidth(
+      getFlagValue(args, "--max-message-width")
+    ),
+    dateFormat: getFlagValue(args, "--date-format"),
+    timeZone: parseTimeZone(getFlagValue(args, "--tz")),
+    pager: !args.includes("--no-pager"),
+    sort: parseSort(getFlagValue(args, "--sort")),
+    groupBy: parseGroupBy(getFlagValue(args, "--group-by")),
+    weekStart: parseWeekStart(getFlagValue(args, "--week-start")),
+    // Output written to a file never gets ANSI colors
+    color: isColorEnabled(args) && outputPath === undefined,
+    // Single colors set in the config file override the theme's
+    colors: {
+      ...theme,
+      head: config.headColor ?? theme.head,
+      border: config.borderColor ?? theme.border,
+      date: config.dateColor ?? theme.date,
+    },
+  };
+
+  if (!display.color) {
+    chalk.level = 0;
+  }
+
+  // Links are escape codes too, so they follow the color setting
+  if (display.color && !args.includes("--no-links") && supportsHyperlinks()) {
+    display.commitUrl = getCommitUrlBase();
+  }
+
+  if (display.outputPath !== undefined) {
+    if (display.outputPath.trim() === "") {
+      throw new WhoError("--output requires a file path, e.g. -o logs.md");
+    }
+    outputFile = { path: display.outputPath, prepared: false };
+  }
+
+  if (command === "top") {
+    await runTop(args, display, config);
+    return;
+  }
+
+  if (command === "bus-factor") {
+    await runBusFactor(args, display, config);
+    return;
+  }
+
+  if (command === "summary") {
+    await runSummary(args, display);
+    return;
+  }
+
+  if (command === "diff") {
+    await runDiff(args, display, config);
+    return;
+  }
+
+  if (command === "churn") {
+    await runChurn(args, display, config);
+    return;
+  }
+
+  if (command === "inactive") {
+    await runInactive(args, display);
+    return;
+  }
+
+  if (command === "onboarding") {
+    await runOnboarding(args, display, config);
+    return;
+  }
+
+  if (command === "compare") {
+    await runCompare(args, display, config);
+    return;
+  }
+
+  if (command === "show") {
+    runShow(args, display);
+    return;
+  }
+
+  if (command === "open") {
+    await runOpen(args, display, config);
+    return;
+  }
+
+  if (command === "email-report") {
+    await runEmailReport(args, display, config);
+    return;
+  }
+
+  if (command === "search") {
+    await runSearch(args, display, config);
+    return;
+  }
+
+  if (command === "tags") {
+    await runTags(args, display, config);
+    return;
+  }
+
+  if (command === "blame") {
+    runBlame(commandArgs[0], display);
+    return;
+  }
+
+  if (command === "heatmap") {
+    await runHeatmap(args, display, config);
+    return;
+  }
+
+  if (command === "standup") {
+    await runStandup(args, display);
+    return;
+  }
+
+  const until = getFlagValue(args, "--until");
+  // Counting looks at every matching commit unless a limit is passed explicitly
+  const limit = parseLimit(
+    getFlagValue(args, "--limit") ??
+      getFlagValue(args, "-n") ??
+      (display.format === "count" ? "0" : config.limit)
+  );
+  const paths = getFlagValues(args, "--path");
+
+  paths
+    .filter((path) => !existsSync(path))
+    .forEach((path) => {
+      console.warn(
+        chalk.yellow(
+          `Warning: ${path} does not exist, showing commits that touched it historically.`
+        )
+      );
+    });
+
+  const follow = args.includes("--follow");
+
+  if (follow && paths.length !== 1) {
+    throw new WhoError(
+      paths.length === 0
+        ? "--follow needs a file, e.g. --follow --path=src/app.ts"
+        : "--follow only works with a single --path, since git cannot follow several files."
+    );
+  }
+
+  const branch = getFlagValue(args, "--branch");
+  const tagRange = resolveTagRange(args, branch);
+  const branchPointRange = resolveBranchPointRange(args, branch);
+
+  if (tagRange !== undefined && branchPointRange !== undefined) {
+    throw new WhoError(
+      "--since-branch-point cannot be combined with --since-tag or --until-tag."
+    );
+  }
+
+  const revisionRange = tagRange ?? branchPointRange;
+
+  if (revisionRange !== undefined && hasFlag(args, "--T")) {
+    throw new WhoError(
+      "--T cannot be combined with --since-tag, --until-tag or --since-branch-point."
+    );
+  }
+
+  const timeRange = revisionRange
+    ? ""
+    : await resolveTimeRange(args, config.timeRange);
+  const noMerges = args.includes("--no-merges");
+  const mergesOnly = args.includes("--merges-only");
+  const includeMerges = args.includes("--include-merges");
+
+  if (noMerges && mergesOnly) {
+    throw new WhoError("--no-merges and --merges-only cannot be combined.");
+  }
+
+  if (includeMerges && noMerges) {
+    throw new WhoError("--include-merges and --no-merges cannot be combined.");
+  }
+
+  // Like the leaderboards, --count-only counts authored work, not merges, so
+  // it matches the rows --no-merges shows; --merges-only counts the merges
+  const excludeMerges =
+    noMerges ||
+    (display.format === "count" && !includeMerges && !mergesOnly);
+
+  const firstParent = args.includes("--first-parent");
+
+  // On the mainline most changes arrive as merges, so hiding them too leaves
+  // only the commits made directly on the branch
+  if (firstParent && noMerges) {
+    console.warn(
+      chalk.yellow(
+        "Warning: --first-parent with --no-merges only shows commits made directly on the branch, not the work that was merged into it."
+      )
+    );
+  }
+
+  if (branch !== undefined) {
+    validateBranch(branch);
+  }
+
+  if (args.includes("--interactive")) {
+    if (args.includes("--pick")) {
+      throw new WhoError("--interactive and --pick cannot be combined.");
+    }
+    if (!process.stdout.isTTY) {
+      throw new WhoError("--interactive needs a terminal.");
+    }
+    requirePrompt(
+      args,
+      "--interactive",
+      "Leave out --interactive to print the table."
+    );
+  }
+
+  if (args.includes("--pick")) {
+    requirePrompt(args, "--pick", "Leave out --pick to print the table.");
+  }
+
+  if (display.format === "ndjson" && display.sort) {
+    throw new WhoError(
+      "--sort cannot be combined with --ndjson, which prints commits as git finds them."
+    );
+  }
+
+  if (until !== undefined) {
+    validateTimeRange(until, "--until");
+  }
+
+  const fetchOptions: FetchOptions = {
+    until,
+    limit,
+    paths,
+    follow,
+    grep: getFlagValue(args, "--grep-i") ?? getFlagValue(args, "--grep"),
+    ignoreCase: hasFlag(args, "--grep-i"),
+    // The summary footer and the Paths column need the changed files too
+    stat: display.showStat || display.showSummary || display.showPaths,
+    signature: display.showSignature,
+    coAuthors: display.showAttribution,
+    dco: display.showDco,
+    dcoMissing: args.includes("--dco-missing"),
+    fixupsOnly: args.includes("--fixups-only"),
+    body: args.includes("--body"),
+    branch,
+    all: args.includes("--all"),
+    includeRemote: args.includes("--include-remote"),
+    firstParent,
+    fullHash: args.includes("--full-hash"),
+    merges: excludeMerges ? "exclude" : mergesOnly ? "only" : undefined,
+    timeZone: display.timeZone,
+    revisionRange,
+    authorRegex: args.includes("--author-regex"),
+    reverse: args.includes("--reverse"),
+    exact: args.includes("--exact"),
+    dateKind: parseDateKind(getFlagValue(args, "--date-kind")),
+    ignoreMailmap: args.includes("--no-mailmap"),
+    order: parseOrder(getFlagValue(args, "--order")),
+  };
+
+  const authors = await resolveAuthors(args, getPositionalArgs(args), config);
+
+  if (args.includes("--dry-run")) {
+    // --first searches the whole history rather than the time range
+    const first = args.includes("--first");
+    const options = first ? getFirstCommitOptions(fetchOptions) : fetchOptions;
+    const identities = resolveIdentities(authors, options);
+    const command = buildLogCommand(
+      identities,
+      first ? "" : timeRange,
+      options
+    );
+    console.log(["git", ...command].map(quoteShellArg).join(" "));
+    return;
+  }
+
+  if (args.includes("--watch")) {
+    await watchLogs(args, authors, timeRange, fetchOptions, display);
+    return;
+  }
+
+  if (args.includes("--first")) {
+    if (!(await showFirstCommit(authors, fetchOptions, display))) {
+      process.exitCode = EXIT_NO_COMMITS;
+    }
+    return;
+  }
+
+  if (display.format === "ndjson") {
+    const count = await displayLogsNdjson(
+      authors,
+      timeRange,
+      fetchOptions,
+      display
+    );
+    if (count === 0) {
+      process.exitCode = EXIT_NO_COMMITS;
+    }
+    return;
+  }
+
+  const logs = await showLogs(authors, timeRange, fetchOptions, display);
+
+  if (logs.length === 0) {
+    process.exitCode = EXIT_NO_COMMITS;
+  }
+
+  if (args.includes("--pick") && display.format === "table" && logs.length) {
+    await pickCommit(logs);
+  }
+
+  if (
+    args.includes("--interactive") &&
+    display.format === "table" &&
+    logs.length
+  ) {
+    await browseCommits(logs, display);
+  }
+};
+
+// Only run as a command, so the tests can import the parsers
+if (import.meta.main) {
+  main()
+    .then(() => {
+      // A successful run that printed nothing still replaces the old file
+      if (outputFile && !outputFile.prepared) {
+        prepareOutputFile(outputFile.path);
+      }
+    })
+    .catch((error) => {
+      process.exit(renderError(error));
+    });
+}